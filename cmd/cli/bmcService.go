@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strconv"
+	"sync"
+)
+
+// mockBMC is the state of the emulated server, shared by the Redfish, IPMI, SSH and VNC services.
+// It answers like a Dell iDRAC9, so the checks of site-manage-server pass with the embedded firmware policy.
+type mockBMC struct {
+	mu          sync.Mutex
+	username    string
+	password    string
+	vncPassword string
+	guid        [16]byte
+	powerOn     bool
+	mediaImage  string
+	// Redfish session tokens and their IDs
+	sessions  map[string]string
+	sessionID int
+}
+
+func newMockBMC(username string, password string, vncPassword string) *mockBMC {
+	bmc := &mockBMC{
+		username:    username,
+		password:    password,
+		vncPassword: vncPassword,
+		powerOn:     true,
+		sessions:    map[string]string{},
+	}
+	rand.Read(bmc.guid[:])
+
+	return bmc
+}
+
+func (bmc *mockBMC) setPower(on bool) {
+	bmc.mu.Lock()
+	defer bmc.mu.Unlock()
+
+	if bmc.powerOn != on {
+		slog.Info(fmt.Sprintf("Mock BMC server powered %s", formatPowerState(on)))
+	}
+	bmc.powerOn = on
+}
+
+func (bmc *mockBMC) power() bool {
+	bmc.mu.Lock()
+	defer bmc.mu.Unlock()
+
+	return bmc.powerOn
+}
+
+func runBMCService(ctx context.Context, endCh chan<- string, app *application, args map[string]string) {
+	slog.Info("Starting BMC mock service", "arguments", args)
+
+	var listenIP netip.Addr
+	strListenIP, ok := args["listen-ip"]
+	if !ok {
+		listenIP = netip.IPv4Unspecified()
+	} else {
+		var err error
+		listenIP, err = netip.ParseAddr(strListenIP)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to parse listen-ip argument (%s): %s", strListenIP, err.Error()))
+			app.setExitCode(exitUsage)
+			endCh <- "BMC mock service failed"
+			return
+		}
+	}
+
+	ports := map[string]uint16{}
+	for _, key := range []string{"redfish-port", "ipmi-port", "ssh-port", "vnc-port"} {
+		port, err := strconv.ParseUint(args[key], 10, 16)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to parse %s argument (%s): %s", key, args[key], err.Error()))
+			app.setExitCode(exitUsage)
+			endCh <- "BMC mock service failed"
+			return
+		}
+		ports[key] = uint16(port)
+	}
+
+	vncPassword := args["vnc-password"]
+	if vncPassword == "" {
+		vncPassword = args["password"]
+	}
+
+	bmc := newMockBMC(args["username"], args["password"], vncPassword)
+
+	// Redfish: TCP port 443
+	app.wg.Add(1)
+	go app.startRedfishServer(ctx, listenIP, ports["redfish-port"], bmc)
+
+	// IPMI over LAN (RMCP+): UDP port 623
+	app.wg.Add(1)
+	go app.startIPMIServer(ctx, listenIP, ports["ipmi-port"], bmc)
+
+	// SSH: TCP port 22
+	app.wg.Add(1)
+	go app.startSSHServer(ctx, listenIP, ports["ssh-port"], sshServerConfig{username: bmc.username, password: bmc.password, prompt: "racadm>> "})
+
+	// Virtual console: TCP port 5901
+	app.wg.Add(1)
+	go app.startVNCServer(ctx, listenIP, ports["vnc-port"], bmc)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BOOT_URI_PREFIX = "/boot/"
+	// Sizes of the test blobs, close to those of the kernel and initrd of the MetalSoft provisioning image
+	BOOT_KERNEL_SIZE = 12 << 20
+	BOOT_INITRD_SIZE = 96 << 20
+)
+
+// bootPattern is the content repeated by the test blobs - random so that compression on the path does not help.
+var bootPattern = func() []byte {
+	pattern := make([]byte, 1<<20)
+	generator := rand.NewChaCha8(sha256.Sum256([]byte("metalsoft-prerequisite-check")))
+	generator.Read(pattern)
+	return pattern
+}()
+
+// bootBlob is a test artifact of the given size generated from bootPattern.
+type bootBlob struct {
+	size int64
+}
+
+func (b bootBlob) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) && off < b.size {
+		end := min(len(p), n+int(min(b.size-off, int64(len(bootPattern)))))
+		copied := copy(p[n:end], bootPattern[off%int64(len(bootPattern)):])
+		n += copied
+		off += int64(copied)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// bootClientStats are the HTTP boot transfers of a client.
+type bootClientStats struct {
+	Requests int `json:"requests"`
+	// Responses written up to the last byte
+	Completed int       `json:"completed"`
+	Bytes     int64     `json:"bytes"`
+	LastPath  string    `json:"last_path"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// bootServer emulates the HTTP boot endpoints of the site controller - an iPXE script that downloads a kernel and
+// an initrd sized test blob. Other requests are answered by httpRequestHandler.
+type bootServer struct {
+	app     *application
+	started time.Time
+	blobs   map[string]bootBlob
+
+	checksumsOnce sync.Once
+	checksums     string
+
+	mu      sync.Mutex
+	clients map[string]*bootClientStats
+}
+
+func newBootServer(app *application) *bootServer {
+	return &bootServer{
+		app:     app,
+		started: time.Now(),
+		blobs: map[string]bootBlob{
+			"vmlinuz":    {size: BOOT_KERNEL_SIZE},
+			"initrd.img": {size: BOOT_INITRD_SIZE},
+		},
+		clients: map[string]*bootClientStats{},
+	}
+}
+
+func (s *bootServer) requestHandler(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutPrefix(r.URL.Path, BOOT_URI_PREFIX)
+	if !ok {
+		s.app.httpRequestHandler(w, r)
+		return
+	}
+
+	slog.Debug(fmt.Sprintf("HTTP boot request received from %s: %s %s %s", r.RemoteAddr, r.Method, r.URL.Path, r.Header.Get("Range")))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	counter := &countingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+	start := time.Now()
+
+	switch name {
+	case "boot.ipxe":
+		w.Header().Set("Content-Type", "text/plain")
+		http.ServeContent(counter, r, name, s.started, strings.NewReader(bootScript("http://"+r.Host)))
+	case TFTP_TEST_FILE:
+		w.Header().Set("Content-Type", "text/plain")
+		http.ServeContent(counter, r, name, s.started, strings.NewReader(string(tftpTestPayload)))
+	case "SHA256SUMS":
+		w.Header().Set("Content-Type", "text/plain")
+		http.ServeContent(counter, r, name, s.started, strings.NewReader(s.sha256sums()))
+	case "stats":
+		s.mu.Lock()
+		data, err := json.Marshal(s.clients)
+		s.mu.Unlock()
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		writeMockJSON(w, json.RawMessage(data))
+		return
+	default:
+		blob, ok := s.blobs[name]
+		if !ok {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		// Large artifacts on a slow link take longer than the write timeout of the server
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			slog.Debug(fmt.Sprintf("Could not clear the write deadline of HTTP boot transfer - %s", err.Error()))
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, name, blob.size))
+		http.ServeContent(counter, r, name, s.started, io.NewSectionReader(blob, 0, blob.size))
+	}
+
+	s.record(r, counter, time.Since(start))
+}
+
+// record adds the transfer to the stats of the client and logs it.
+func (s *bootServer) record(r *http.Request, counter *countingResponseWriter, elapsed time.Duration) {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	expected, err := strconv.ParseInt(counter.Header().Get("Content-Length"), 10, 64)
+	complete := err == nil && counter.bytes == expected
+	if r.Method == http.MethodHead {
+		expected, complete = 0, true
+	}
+
+	s.mu.Lock()
+	stats, ok := s.clients[client]
+	if !ok {
+		stats = &bootClientStats{}
+		s.clients[client] = stats
+	}
+	stats.Requests++
+	if complete {
+		stats.Completed++
+	}
+	stats.Bytes += counter.bytes
+	stats.LastPath = r.URL.Path
+	stats.LastSeen = time.Now()
+	total := *stats
+	s.mu.Unlock()
+
+	message := fmt.Sprintf("HTTP boot transfer of %s to %s - status %d, %d of %d bytes in %s (%.1f MiB/s), client total %d bytes in %d requests",
+		r.URL.Path, client, counter.status, counter.bytes, expected, elapsed.Round(time.Millisecond),
+		float64(counter.bytes)/(1<<20)/max(elapsed.Seconds(), 0.001), total.Bytes, total.Requests)
+	if complete {
+		slog.Info(message)
+	} else {
+		slog.Warn(message + " - incomplete")
+	}
+}
+
+// sha256sums lists the checksums of the test blobs in the format of sha256sum, computed on first use.
+func (s *bootServer) sha256sums() string {
+	s.checksumsOnce.Do(func() {
+		var sums strings.Builder
+		for _, name := range []string{"vmlinuz", "initrd.img"} {
+			blob := s.blobs[name]
+			hash := sha256.New()
+			io.Copy(hash, io.NewSectionReader(blob, 0, blob.size))
+			fmt.Fprintf(&sums, "%s  %s\n", hex.EncodeToString(hash.Sum(nil)), name)
+		}
+		s.checksums = sums.String()
+	})
+
+	return s.checksums
+}
+
+// bootScript downloads the test blobs from the site controller without booting them.
+func bootScript(base string) string {
+	return `#!ipxe
+echo MetalSoft prerequisite check - HTTP boot from ` + base + `
+imgfetch --name kernel ` + base + BOOT_URI_PREFIX + `vmlinuz || goto failed
+imgfetch --name initrd ` + base + BOOT_URI_PREFIX + `initrd.img || goto failed
+imgstat
+echo The kernel and the initrd were downloaded from the site controller.
+imgfree
+goto end
+:failed
+echo The download from the site controller failed.
+:end
+prompt --timeout 30000 Press any key to open the iPXE shell... && shell || exit
+`
+}
+
+// countingResponseWriter counts the bytes of the response body.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *countingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *countingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
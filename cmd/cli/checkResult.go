@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type CheckStatus string
+
+const (
+	StatusPass CheckStatus = "pass"
+	StatusWarn CheckStatus = "warn"
+	StatusFail CheckStatus = "fail"
+	StatusSkip CheckStatus = "skip"
+)
+
+// CheckResult is the outcome of a single probe against a single target.
+type CheckResult struct {
+	ID         string            `json:"id"`
+	Device     string            `json:"device,omitempty"`
+	Name       string            `json:"name"`
+	Target     string            `json:"target"`
+	Protocol   string            `json:"protocol"`
+	Port       int               `json:"port,omitempty"`
+	Status     CheckStatus       `json:"status"`
+	Duration   time.Duration     `json:"-"`
+	ErrorClass string            `json:"error_class,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Facts      map[string]string `json:"facts,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Attempts   []checkAttempt    `json:"attempts,omitempty"`
+
+	started time.Time
+}
+
+type checkResults []CheckResult
+
+func (r CheckResult) MarshalJSON() ([]byte, error) {
+	type alias CheckResult
+	return json.Marshal(struct {
+		alias
+		DurationMs int64 `json:"duration_ms"`
+	}{
+		alias:      alias(r),
+		DurationMs: r.Duration.Milliseconds(),
+	})
+}
+
+// statusError is returned by probes that got a response with an unexpected HTTP status.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("response status %s", e.status)
+}
+
+func newCheckResult(protocol string, target string, port int) CheckResult {
+	name := protocol
+	if port > 0 {
+		name += " " + strconv.Itoa(port)
+	}
+	name += " " + target
+
+	id := strings.ToLower(protocol) + ":" + target
+	if port > 0 {
+		id += ":" + strconv.Itoa(port)
+	}
+
+	return CheckResult{
+		ID:       id,
+		Name:     name,
+		Target:   target,
+		Protocol: protocol,
+		Port:     port,
+		started:  time.Now(),
+	}
+}
+
+// newLinkCheckResult derives the protocol and port of the check from the link.
+func newLinkCheckResult(link string) CheckResult {
+	protocol := "HTTP"
+	port := 80
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return newCheckResult(protocol, link, 0)
+	}
+
+	if strings.EqualFold(parsed.Scheme, "https") {
+		protocol = "HTTPS"
+		port = 443
+	}
+	if parsed.Port() != "" {
+		port, _ = strconv.Atoi(parsed.Port())
+	}
+
+	result := newCheckResult(protocol, parsed.Hostname(), port)
+	result.addFact("url", link)
+
+	return result
+}
+
+func (r *CheckResult) addFact(key string, value string) {
+	if value == "" {
+		return
+	}
+	if r.Facts == nil {
+		r.Facts = make(map[string]string)
+	}
+	r.Facts[key] = value
+}
+
+func (r *CheckResult) finish(status CheckStatus, err error) CheckResult {
+	r.Status = status
+	r.Duration = time.Since(r.started)
+	if err != nil {
+		r.ErrorClass = classifyError(err)
+		r.Error = err.Error()
+	}
+
+	return *r
+}
+
+func (r *CheckResult) pass() CheckResult {
+	return r.finish(StatusPass, nil)
+}
+
+func (r *CheckResult) warn(err error) CheckResult {
+	return r.finish(StatusWarn, err)
+}
+
+func (r *CheckResult) fail(err error) CheckResult {
+	return r.finish(StatusFail, err)
+}
+
+func (r *CheckResult) skip(reason string) CheckResult {
+	r.Error = reason
+	return r.finish(StatusSkip, nil)
+}
+
+func classifyError(err error) string {
+	if errors.Is(err, errAccountLocked) {
+		return "account-locked"
+	}
+
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.code {
+		case http.StatusUnauthorized:
+			return "auth"
+		case http.StatusForbidden:
+			return "forbidden"
+		default:
+			return "http-status"
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return "dns"
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return "timeout"
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return "connection-refused"
+	}
+
+	if errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH) {
+		return "unreachable"
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	if errors.As(err, &certErr) || errors.As(err, &recordErr) || errors.As(err, &authorityErr) {
+		return "tls"
+	}
+
+	if strings.Contains(err.Error(), "unable to authenticate") {
+		return "auth"
+	}
+
+	return "error"
+}
+
+func (results checkResults) count(status CheckStatus) int {
+	count := 0
+	for _, result := range results {
+		if result.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+// summarize logs the outcome of a check command and records its results with the application.
+func (app *application) summarize(title string, results checkResults) {
+	app.mu.Lock()
+	app.results = append(app.results, results...)
+	app.mu.Unlock()
+
+	for _, result := range results {
+		if result.Status == StatusFail {
+			slog.Debug(fmt.Sprintf("Failed check %s (%s) - %s", result.Name, result.ErrorClass, result.Error))
+		}
+	}
+
+	if failures := results.count(StatusFail); failures > 0 {
+		slog.Error(fmt.Sprintf("%s detected %d problems", title, failures))
+		app.setExitCode(exitFailures)
+	} else if warnings := results.count(StatusWarn); warnings > 0 {
+		slog.Warn(fmt.Sprintf("%s detected no problems and %d warnings", title, warnings))
+		app.setExitCode(exitWarnings)
+	} else {
+		slog.Info(fmt.Sprintf("%s detected no problems", title))
+	}
+}
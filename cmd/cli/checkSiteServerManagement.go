@@ -5,58 +5,61 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
-	"strings"
 )
 
+// Columns of the server inventory file
+var serverInventoryColumns = argumentsList{
+	{key: "vendor", required: true},
+	{key: "bmc-ip", required: true},
+	{key: "username", required: true},
+	{key: "password", required: true, sensitive: true},
+	{key: "vnc-port"},
+	{key: "vnc-password", sensitive: true},
+}
+
 func checkSiteServerManagement(ctx context.Context, endCh chan<- string, app *application, args map[string]string) {
 	slog.Info("Starting Site Controller server management check", "arguments", args)
 
-	serverVendor := strings.ToLower(args["vendor"])
-	bmcIP := args["bmc-ip"]
-	username := args["username"]
-	password := args["password"]
-	vncPort, err := strconv.Atoi(args["vnc-port"])
+	policy, err := loadFirmwarePolicy(args["firmware-policy"])
 	if err != nil {
-		slog.Error(fmt.Sprintf("Failed to parse vnc-port argument (%s): %s", args["vnc-port"], err.Error()))
+		slog.Error(fmt.Sprintf("Failed to load firmware policy %s - %s", args["firmware-policy"], err.Error()))
+		app.setExitCode(exitUsage)
 		endCh <- "Site Controller server management check failed"
 		return
 	}
-	vncPassword := args["vnc-password"]
+	app.firmwarePolicy = policy
 
-	errors := 0
+	if inventory := args["inventory"]; inventory != "" {
+		rows, err := loadInventory(inventory, args, serverInventoryColumns)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to load server inventory %s - %s", inventory, err.Error()))
+			app.setExitCode(exitUsage)
+			endCh <- "Site Controller server management check failed"
+			return
+		}
 
-	// Redfish - TCP port 443
-	errors += app.testHTTPSConnection(ctx, bmcIP, 443)
-	result, err := app.testRedfishAPI(ctx, bmcIP, 443, username, password, "/redfish/v1")
-	if err != nil {
-		errors++
-	} else {
-		data := result.(map[string]interface{})
-		slog.Debug(fmt.Sprintf("Received Redfish response from %s:%d\n  RedfishVersion: %s\n  Vendor: %s",
-			bmcIP,
-			443,
-			safeConvert(data, "RedfishVersion"),
-			safeConvert(data, "Vendor")))
-	}
+		slog.Info(fmt.Sprintf("Checking %d servers from inventory %s", len(rows), inventory))
 
-	// SSH - TCP port 22
-	errors += app.testSSHConnection(ctx, bmcIP, 22, username, password)
+		results := app.runInventory(ctx, "site-manage-server", rows, "bmc-ip")
 
-	// IPMI - UDP port 623
-	errors += app.testIPMIConnection(ctx, bmcIP, 623, username, password)
+		logDeviceMatrix("Server", results)
+		app.summarize("Site Controller server management check", results)
 
-	if serverVendor == "dell" && vncPassword != "" {
-		// Dell iDRAC VNC - TCP port 5901
-		errors += app.testVNCConnection(ctx, bmcIP, vncPort, vncPassword)
+		endCh <- "Site Controller server management check completed"
+		return
 	}
 
-	// TODO: Add test for virtual media mounting
-
-	if errors > 0 {
-		slog.Error(fmt.Sprintf("Site Controller server management check detected %d problems", errors))
-	} else {
-		slog.Info("Site Controller server management check detected no problems")
+	_, err = strconv.Atoi(args["vnc-port"])
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to parse vnc-port argument (%s): %s", args["vnc-port"], err.Error()))
+		app.setExitCode(exitUsage)
+		endCh <- "Site Controller server management check failed"
+		return
 	}
 
+	results := app.runEmbeddedProfile(ctx, "site-manage-server", args)
+
+	app.summarize("Site Controller server management check", results)
+
 	endCh <- "Site Controller server management check completed"
 }
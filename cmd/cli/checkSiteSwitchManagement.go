@@ -4,38 +4,42 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strings"
 )
 
+// Columns of the switch inventory file
+var switchInventoryColumns = argumentsList{
+	{key: "nos", required: true},
+	{key: "management-ip", required: true},
+	{key: "username", required: true},
+	{key: "password", required: true, sensitive: true},
+}
+
 func checkSiteSwitchManagement(ctx context.Context, endCh chan<- string, app *application, args map[string]string) {
 	slog.Info("Starting Site Controller switch management check", "arguments", args)
 
-	switchNos := strings.ToLower(args["nos"])
-	switchIP := args["management-ip"]
-	username := args["username"]
-	password := args["password"]
-
-	errors := 0
+	if inventory := args["inventory"]; inventory != "" {
+		rows, err := loadInventory(inventory, args, switchInventoryColumns)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to load switch inventory %s - %s", inventory, err.Error()))
+			app.setExitCode(exitUsage)
+			endCh <- "Site Controller switch management check failed"
+			return
+		}
 
-	// HTTP - TCP port 80
-	errors += app.testHTTPConnection(ctx, switchIP, 80)
+		slog.Info(fmt.Sprintf("Checking %d switches from inventory %s", len(rows), inventory))
 
-	// HTTPS - TCP port 443
-	errors += app.testHTTPSConnection(ctx, switchIP, 443)
+		results := app.runInventory(ctx, "site-manage-switch", rows, "management-ip")
 
-	// SSH - TCP port 22
-	errors += app.testSSHConnection(ctx, switchIP, 22, username, password)
+		logDeviceMatrix("Switch", results)
+		app.summarize("Site Controller switch management check", results)
 
-	if switchNos == "junos" {
-		// NETCONF/SSH - TCP port 830
-		errors += app.testSSHConnection(ctx, switchIP, 830, username, password)
+		endCh <- "Site Controller switch management check completed"
+		return
 	}
 
-	if errors > 0 {
-		slog.Error(fmt.Sprintf("Site Controller switch management check detected %d problems", errors))
-	} else {
-		slog.Info("Site Controller switch management check detected no problems")
-	}
+	results := app.runEmbeddedProfile(ctx, "site-manage-switch", args)
+
+	app.summarize("Site Controller switch management check", results)
 
 	endCh <- "Site Controller switch management check completed"
 }
@@ -9,6 +9,10 @@ type argumentDetails struct {
 	description  string
 	required     bool
 	defaultValue string
+	// The argument is not required when this other argument is set
+	requiredUnless string
+	// Sensitive values are redacted from logs and reports
+	sensitive bool
 }
 
 type argumentsList []argumentDetails
@@ -17,7 +21,10 @@ type commandDetails struct {
 	key         string
 	description string
 	arguments   argumentsList
-	handler     func(context.Context, chan<- string, *application, map[string]string)
+	// Accept arguments not listed in arguments - validated by the handler
+	anyArguments bool
+	service      bool
+	handler      func(context.Context, chan<- string, *application, map[string]string)
 }
 
 type commandsList []commandDetails
@@ -65,6 +72,7 @@ var commands = commandsList{
 				defaultValue: "0.0.0.0",
 			},
 		},
+		service: true,
 		handler: runGlobalService,
 	},
 	{
@@ -95,24 +103,34 @@ var commands = commandsList{
 		description: "Checks site controller access to manage switch.",
 		arguments: argumentsList{
 			{
-				key:         "nos",
-				description: "The switch NOS - one of (OS10, SONiC, JunOS, Cisco).",
-				required:    true,
+				key:            "nos",
+				description:    "The switch NOS - one of (OS10, SONiC, JunOS, Cisco).",
+				required:       true,
+				requiredUnless: "inventory",
 			},
 			{
-				key:         "management-ip",
-				description: "IP address of the switch management port.",
-				required:    true,
+				key:            "management-ip",
+				description:    "IP address of the switch management port.",
+				required:       true,
+				requiredUnless: "inventory",
 			},
 			{
-				key:         "username",
-				description: "Username of the switch management admin user.",
-				required:    true,
+				key:            "username",
+				description:    "Username of the switch management admin user.",
+				required:       true,
+				requiredUnless: "inventory",
 			},
 			{
-				key:         "password",
-				description: "Password of the switch management admin user.",
-				required:    true,
+				key:            "password",
+				description:    "Password of the switch management admin user.",
+				required:       true,
+				requiredUnless: "inventory",
+				sensitive:      true,
+			},
+			{
+				key:         "inventory",
+				description: "CSV file with one switch per row - columns nos, management-ip, username, password. Empty cells take the value of the argument, passwords may reference env:<variable> or file:<path>.",
+				required:    false,
 			},
 		},
 		handler: checkSiteSwitchManagement,
@@ -122,24 +140,29 @@ var commands = commandsList{
 		description: "Checks site controller access to manage server.",
 		arguments: argumentsList{
 			{
-				key:         "vendor",
-				description: "The server vendor - one of (Dell, HP, Lenovo).",
-				required:    true,
+				key:            "vendor",
+				description:    "The server vendor - one of (Dell, HP, Lenovo).",
+				required:       true,
+				requiredUnless: "inventory",
 			},
 			{
-				key:         "bmc-ip",
-				description: "IP address of the server BMC interface.",
-				required:    true,
+				key:            "bmc-ip",
+				description:    "IP address of the server BMC interface.",
+				required:       true,
+				requiredUnless: "inventory",
 			},
 			{
-				key:         "username",
-				description: "Username of the server BMC admin user.",
-				required:    true,
+				key:            "username",
+				description:    "Username of the server BMC admin user.",
+				required:       true,
+				requiredUnless: "inventory",
 			},
 			{
-				key:         "password",
-				description: "Password of the server BMC admin user.",
-				required:    true,
+				key:            "password",
+				description:    "Password of the server BMC admin user.",
+				required:       true,
+				requiredUnless: "inventory",
+				sensitive:      true,
 			},
 			{
 				key:          "vnc-port",
@@ -151,15 +174,49 @@ var commands = commandsList{
 				key:         "vnc-password",
 				description: "VNC password.",
 				required:    false,
+				sensitive:   true,
+			},
+			{
+				key:         "inventory",
+				description: "CSV file with one server per row - columns vendor, bmc-ip, username, password, vnc-port, vnc-password. Empty cells take the value of the argument, passwords may reference env:<variable> or file:<path>.",
+				required:    false,
 			},
 			{
 				key:         "iso-link",
 				description: "Link to an ISO to test mounting virtual media.",
 				required:    false,
 			},
+			{
+				key:         "firmware-policy",
+				description: "YAML file with minimum BMC and BIOS firmware versions - rules replace the embedded rules with the same name.",
+				required:    false,
+			},
 		},
 		handler: checkSiteServerManagement,
 	},
+	{
+		key:         "dhcp-probe",
+		description: "Detects the DHCP servers answering on the provisioning network.",
+		arguments: argumentsList{
+			{
+				key:         "interface",
+				description: "Network interface on which to broadcast the DHCP discover.",
+				required:    true,
+			},
+			{
+				key:          "window",
+				description:  "How long to collect the DHCP offers.",
+				required:     false,
+				defaultValue: "5s",
+			},
+			{
+				key:         "dhcp-server",
+				description: "IP address of the authorized DHCP server - offers from any other server fail the check.",
+				required:    false,
+			},
+		},
+		handler: checkDHCPServers,
+	},
 	{
 		key:         "site-service",
 		description: "Runs global controller emulation service.",
@@ -170,7 +227,172 @@ var commands = commandsList{
 				required:     false,
 				defaultValue: "0.0.0.0",
 			},
+			{
+				key:         "dhcp-pool",
+				description: "Range of addresses offered by the DHCP responder, e.g. 192.168.100.100-192.168.100.199. Without it DHCP packets are only logged.",
+				required:    false,
+			},
+			{
+				key:          "dhcp-netmask",
+				description:  "Subnet mask offered by the DHCP responder.",
+				required:     false,
+				defaultValue: "255.255.255.0",
+			},
+			{
+				key:         "dhcp-router",
+				description: "Default gateway offered by the DHCP responder.",
+				required:    false,
+			},
+			{
+				key:         "dhcp-next-server",
+				description: "Boot server offered by the DHCP responder as next-server and option 66.",
+				required:    false,
+			},
+			{
+				key:         "dhcp-bootfile",
+				description: "Boot file offered by the DHCP responder in the file field and option 67.",
+				required:    false,
+			},
+			{
+				key:          "dhcp-lease-time",
+				description:  "Lease time of the DHCP responder.",
+				required:     false,
+				defaultValue: "1h",
+			},
 		},
+		service: true,
 		handler: runSiteService,
 	},
+	{
+		key:         "bmc-service",
+		description: "Runs BMC emulation service.",
+		arguments: argumentsList{
+			{
+				key:          "listen-ip",
+				description:  "IP address to listen on.",
+				required:     false,
+				defaultValue: "0.0.0.0",
+			},
+			{
+				key:          "username",
+				description:  "Username accepted by the Redfish, IPMI and SSH services.",
+				required:     false,
+				defaultValue: "root",
+			},
+			{
+				key:          "password",
+				description:  "Password accepted by the Redfish, IPMI and SSH services.",
+				required:     false,
+				defaultValue: "calvin",
+				sensitive:    true,
+			},
+			{
+				key:         "vnc-password",
+				description: "Password of the VNC service. Defaults to the password argument.",
+				required:    false,
+				sensitive:   true,
+			},
+			{
+				key:          "redfish-port",
+				description:  "TCP port of the Redfish service.",
+				required:     false,
+				defaultValue: "443",
+			},
+			{
+				key:          "ipmi-port",
+				description:  "UDP port of the IPMI service.",
+				required:     false,
+				defaultValue: "623",
+			},
+			{
+				key:          "ssh-port",
+				description:  "TCP port of the SSH service.",
+				required:     false,
+				defaultValue: "22",
+			},
+			{
+				key:          "vnc-port",
+				description:  "TCP port of the VNC service.",
+				required:     false,
+				defaultValue: "5901",
+			},
+		},
+		service: true,
+		handler: runBMCService,
+	},
+	{
+		key:         "switch-service",
+		description: "Runs switch emulation service.",
+		arguments: argumentsList{
+			{
+				key:          "listen-ip",
+				description:  "IP address to listen on.",
+				required:     false,
+				defaultValue: "0.0.0.0",
+			},
+			{
+				key:          "nos",
+				description:  "The emulated switch NOS - one of (OS10, SONiC, JunOS, Cisco).",
+				required:     false,
+				defaultValue: "JunOS",
+			},
+			{
+				key:          "username",
+				description:  "Username accepted by the SSH, NETCONF and management API services.",
+				required:     false,
+				defaultValue: "admin",
+			},
+			{
+				key:          "password",
+				description:  "Password accepted by the SSH, NETCONF and management API services.",
+				required:     false,
+				defaultValue: "admin",
+				sensitive:    true,
+			},
+			{
+				key:          "http-port",
+				description:  "TCP port of the HTTP service.",
+				required:     false,
+				defaultValue: "80",
+			},
+			{
+				key:          "https-port",
+				description:  "TCP port of the HTTPS service.",
+				required:     false,
+				defaultValue: "443",
+			},
+			{
+				key:          "ssh-port",
+				description:  "TCP port of the SSH service.",
+				required:     false,
+				defaultValue: "22",
+			},
+			{
+				key:          "netconf-port",
+				description:  "TCP port of the NETCONF over SSH service.",
+				required:     false,
+				defaultValue: "830",
+			},
+		},
+		service: true,
+		handler: runSwitchService,
+	},
+	{
+		key:         "run-profile",
+		description: "Runs the checks described in a profile file. Additional arguments are passed to the profile.",
+		arguments: argumentsList{
+			{
+				key:         "file",
+				description: "Path of the YAML or JSON profile file.",
+				required:    true,
+			},
+			{
+				key:         "tags",
+				description: "Comma separated list of tags - only checks with one of the tags are executed.",
+				required:    false,
+			},
+		},
+		anyArguments: true,
+		handler:      runProfileCommand,
+	},
 }
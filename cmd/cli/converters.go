@@ -1,11 +1,104 @@
 package main
 
+import "strings"
+
+// safeConvert returns the string value of a key, or nothing when the value is missing, null or not a string.
 func safeConvert(data interface{}, key string) string {
-	if data == nil {
+	object, ok := data.(map[string]interface{})
+	if !ok {
 		return ""
 	}
-	if value, ok := data.(map[string]interface{})[key]; ok {
-		return value.(string)
+	value, _ := object[key].(string)
+	return value
+}
+
+// safeMap returns the nested object of a parsed JSON document.
+func safeMap(data interface{}, key string) map[string]interface{} {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	value, _ := object[key].(map[string]interface{})
+	return value
+}
+
+// safeLink returns the @odata.id of a linked Redfish resource.
+func safeLink(data interface{}, key string) string {
+	link, _ := safeMap(data, key)["@odata.id"].(string)
+	return link
+}
+
+// safeMembers returns the @odata.id of all members of a Redfish collection.
+func safeMembers(data interface{}) []string {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	members, _ := object["Members"].([]interface{})
+
+	links := []string{}
+	for _, member := range members {
+		object, _ := member.(map[string]interface{})
+		if link, ok := object["@odata.id"].(string); ok {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+func safeBool(data interface{}, key string) bool {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	value, _ := object[key].(bool)
+	return value
+}
+
+func safeStrings(data interface{}, key string) []string {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	values, _ := object[key].([]interface{})
+
+	strs := []string{}
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
+
+// findStrings collects the string values of a key anywhere in a parsed JSON document.
+// Keys qualified with a YANG module name, e.g. "openconfig-system:role", also match.
+func findStrings(data interface{}, key string) []string {
+	values := []string{}
+
+	switch typed := data.(type) {
+	case map[string]interface{}:
+		for name, value := range typed {
+			if name == key || strings.HasSuffix(name, ":"+key) {
+				if text, ok := value.(string); ok {
+					values = append(values, text)
+					continue
+				}
+			}
+			values = append(values, findStrings(value, key)...)
+		}
+	case []interface{}:
+		for _, value := range typed {
+			values = append(values, findStrings(value, key)...)
+		}
+	}
+
+	return values
+}
+
+func firstString(values []string) string {
+	if len(values) == 0 {
+		return ""
 	}
-	return ""
+	return values[0]
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Suffixes of the arguments that provide the value of a sensitive argument indirectly
+const (
+	SECRET_ENV_SUFFIX  = "-env"
+	SECRET_FILE_SUFFIX = "-file"
+)
+
+// secretSource splits an argument name like password-env into the sensitive argument and the source suffix.
+func secretSource(arguments argumentsList, argName string) (*argumentDetails, string) {
+	for _, suffix := range []string{SECRET_ENV_SUFFIX, SECRET_FILE_SUFFIX} {
+		key, found := strings.CutSuffix(argName, suffix)
+		if !found {
+			continue
+		}
+
+		for index := range arguments {
+			if arguments[index].key == key && arguments[index].sensitive {
+				return &arguments[index], suffix
+			}
+		}
+	}
+
+	return nil, ""
+}
+
+// isSecretSource recognizes argument names like password-env, resolved by the command handler when the command
+// accepts arguments it does not declare.
+func isSecretSource(argName string) bool {
+	return strings.HasSuffix(argName, SECRET_ENV_SUFFIX) || strings.HasSuffix(argName, SECRET_FILE_SUFFIX)
+}
+
+// resolveProfileSecrets reads the sensitive arguments of a profile given as <argument>-env or <argument>-file
+// and prompts for the required ones that are missing.
+func resolveProfileSecrets(profile *checkProfile, args map[string]string) error {
+	sensitive := func(key string) bool {
+		index := slices.IndexFunc(profile.Arguments, func(argument profileArgument) bool { return argument.Key == key })
+		return (index != -1 && profile.Arguments[index].Sensitive) || isSensitiveKey(key)
+	}
+
+	for _, argName := range slices.Sorted(maps.Keys(args)) {
+		for _, suffix := range []string{SECRET_ENV_SUFFIX, SECRET_FILE_SUFFIX} {
+			key, found := strings.CutSuffix(argName, suffix)
+			if !found || !sensitive(key) {
+				continue
+			}
+
+			secret, err := readSecret(suffix, args[argName])
+			if err != nil {
+				return fmt.Errorf("failed to read %s from %s - %w", key, argName, err)
+			}
+			registerSensitiveKey(key)
+			registerSecret(secret)
+			args[key] = secret
+			delete(args, argName)
+		}
+	}
+
+	for _, argument := range profile.Arguments {
+		if !argument.Sensitive || !argument.Required || args[argument.Key] != "" || argument.DefaultValue != "" {
+			continue
+		}
+		// Prompt for secrets omitted from the command line to keep them out of the shell history
+		if secret, err := promptSecret(argumentDetails{key: argument.Key, description: argument.Description}); err == nil && secret != "" {
+			args[argument.Key] = secret
+		}
+	}
+
+	return nil
+}
+
+// readSecret resolves the value of a sensitive argument from an environment variable or a file.
+func readSecret(suffix string, reference string) (string, error) {
+	switch suffix {
+	case SECRET_ENV_SUFFIX:
+		value, ok := os.LookupEnv(reference)
+		if !ok || value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", reference)
+		}
+		return value, nil
+	case SECRET_FILE_SUFFIX:
+		data, err := os.ReadFile(reference)
+		if err != nil {
+			return "", err
+		}
+		value := strings.TrimRight(string(data), "\r\n")
+		if value == "" {
+			return "", fmt.Errorf("file %s is empty", reference)
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown secret source %s", suffix)
+	}
+}
+
+// promptSecret reads a sensitive argument from the terminal without echo.
+func promptSecret(argDetails argumentDetails) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("standard input is not a terminal")
+	}
+
+	fmt.Fprintf(os.Stderr, "%s (%s): ", argDetails.key, argDetails.description)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(value), nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+// The probe presents itself as a PXE client so that servers answering only PXE boots reply too
+const DHCP_PROBE_CLASS_IDENTIFIER = "PXEClient:Arch:00000:UNDI:002001"
+
+func checkDHCPServers(ctx context.Context, endCh chan<- string, app *application, args map[string]string) {
+	slog.Info("Starting DHCP server detection", "arguments", args)
+
+	window, err := time.ParseDuration(args["window"])
+	if err != nil || window <= 0 {
+		slog.Error(fmt.Sprintf("Failed to parse window argument (%s) - expected a duration, e.g. 5s", args["window"]))
+		app.setExitCode(exitUsage)
+		endCh <- "DHCP server detection failed"
+		return
+	}
+
+	var authorized net.IP
+	if args["dhcp-server"] != "" {
+		authorized = net.ParseIP(args["dhcp-server"]).To4()
+		if authorized == nil {
+			slog.Error(fmt.Sprintf("Failed to parse dhcp-server argument (%s) - expected an IPv4 address", args["dhcp-server"]))
+			app.setExitCode(exitUsage)
+			endCh <- "DHCP server detection failed"
+			return
+		}
+	}
+
+	results := app.probeDHCPServers(ctx, args["interface"], window, authorized)
+
+	app.summarize("DHCP server detection", results)
+
+	endCh <- "DHCP server detection completed"
+}
+
+// probeDHCPServers broadcasts a DISCOVER on the interface and reports every server that offers an address during the window.
+// Without an authorized server more than one answering server is reported as a warning.
+func (app *application) probeDHCPServers(ctx context.Context, ifaceName string, window time.Duration, authorized net.IP) checkResults {
+	slog.Debug(fmt.Sprintf("Probing DHCP servers on interface %s for %s", ifaceName, window))
+
+	result := newCheckResult("DHCP", ifaceName, dhcpv4.ServerPort)
+
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to find interface %s - %s", ifaceName, err.Error()))
+		return checkResults{result.fail(err)}
+	}
+
+	// The raw socket requires elevated permissions
+	conn, err := openDHCPProbeConn(ifaceName)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to open DHCP client socket on interface %s - %s", ifaceName, err.Error()))
+		return checkResults{result.fail(err)}
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	discover, err := dhcpv4.NewDiscovery(iface.HardwareAddr,
+		dhcpv4.WithBroadcast(true),
+		dhcpv4.WithRequestedOptions(dhcpv4.OptionSubnetMask, dhcpv4.OptionRouter, dhcpv4.OptionDomainNameServer, dhcpv4.OptionTFTPServerName, dhcpv4.OptionBootfileName),
+		dhcpv4.WithOption(dhcpv4.OptClassIdentifier(DHCP_PROBE_CLASS_IDENTIFIER)),
+		dhcpv4.WithOption(dhcpv4.OptClientArch(iana.INTEL_X86PC)),
+	)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to build DHCP discover for interface %s - %s", ifaceName, err.Error()))
+		return checkResults{result.fail(err)}
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(window)); err != nil {
+		slog.Error(fmt.Sprintf("Failed to set deadline for DHCP client socket on interface %s - %s", ifaceName, err.Error()))
+		return checkResults{result.fail(err)}
+	}
+
+	if _, err := conn.WriteTo(discover.ToBytes(), &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpv4.ServerPort}); err != nil {
+		slog.Error(fmt.Sprintf("Failed to send DHCP discover on interface %s - %s", ifaceName, err.Error()))
+		return checkResults{result.fail(err)}
+	}
+
+	slog.Debug(fmt.Sprintf("Sent DHCP discover on interface %s - %s", ifaceName, discover.Summary()))
+
+	// Offers by server, in the order they were received
+	var servers []string
+	offers := map[string]*dhcpv4.DHCPv4{}
+
+	buffer := make([]byte, 1500)
+	for {
+		bytesRead, peer, err := conn.ReadFrom(buffer)
+		if err != nil {
+			var netErr net.Error
+			if !(errors.As(err, &netErr) && netErr.Timeout()) && ctx.Err() == nil {
+				slog.Error(fmt.Sprintf("Failed to read DHCP packet on interface %s - %s", ifaceName, err.Error()))
+			}
+			break
+		}
+
+		offer, err := dhcpv4.FromBytes(buffer[:bytesRead])
+		if err != nil || offer.OpCode != dhcpv4.OpcodeBootReply || offer.TransactionID != discover.TransactionID || offer.MessageType() != dhcpv4.MessageTypeOffer {
+			continue
+		}
+
+		server := peer.(*net.UDPAddr).IP.String()
+		if id := offer.ServerIdentifier(); id != nil {
+			server = id.String()
+		}
+		if _, ok := offers[server]; ok {
+			continue
+		}
+
+		slog.Info(fmt.Sprintf("DHCP offer received from %s on interface %s - %s", server, ifaceName, offer.Summary()))
+
+		servers = append(servers, server)
+		offers[server] = offer
+	}
+
+	results := checkResults{}
+	for _, server := range servers {
+		results = append(results, dhcpOfferResult(ifaceName, server, offers[server], len(servers), authorized))
+	}
+
+	if authorized != nil {
+		if _, ok := offers[authorized.String()]; !ok {
+			slog.Error(fmt.Sprintf("No DHCP offer received from %s on interface %s in %s", authorized, ifaceName, window))
+			result = newCheckResult("DHCP", authorized.String(), dhcpv4.ServerPort)
+			result.addFact("Interface", ifaceName)
+			results = append(results, result.fail(fmt.Errorf("no DHCP offer received from the authorized server in %s", window)))
+		}
+		return results
+	}
+
+	if len(servers) == 0 {
+		slog.Error(fmt.Sprintf("No DHCP offer received on interface %s in %s", ifaceName, window))
+		return checkResults{result.fail(fmt.Errorf("no DHCP offer received in %s", window))}
+	}
+
+	return results
+}
+
+// dhcpOfferResult reports the offer of a server and whether the server is expected to answer.
+func dhcpOfferResult(ifaceName string, server string, offer *dhcpv4.DHCPv4, count int, authorized net.IP) CheckResult {
+	result := newCheckResult("DHCP", server, dhcpv4.ServerPort)
+
+	result.addFact("Interface", ifaceName)
+	result.addFact("OfferedIP", offer.YourIPAddr.String())
+	if mask := offer.SubnetMask(); mask != nil {
+		result.addFact("SubnetMask", net.IP(mask).String())
+	}
+	if routers := offer.Router(); len(routers) > 0 {
+		result.addFact("Router", routers[0].String())
+	}
+	if !offer.ServerIPAddr.IsUnspecified() {
+		result.addFact("NextServer", offer.ServerIPAddr.String())
+	}
+	result.addFact("BootFile", offer.BootFileName)
+	result.addFact("TFTPServerName", offer.TFTPServerName())
+	result.addFact("BootFileName", offer.BootFileNameOption())
+	result.addFact("ClassIdentifier", offer.ClassIdentifier())
+	if !offer.GatewayIPAddr.IsUnspecified() {
+		result.addFact("RelayAgent", offer.GatewayIPAddr.String())
+	}
+	if lease := offer.IPAddressLeaseTime(0); lease > 0 {
+		result.addFact("LeaseTime", lease.String())
+	}
+
+	if authorized != nil {
+		if server != authorized.String() {
+			slog.Error(fmt.Sprintf("DHCP server %s answered on interface %s - only %s is expected", server, ifaceName, authorized))
+			result = result.fail(fmt.Errorf("rogue DHCP server - only %s is expected to answer", authorized))
+			result.ErrorClass = "rogue-server"
+			return result
+		}
+		return result.pass()
+	}
+
+	if count > 1 {
+		slog.Warn(fmt.Sprintf("DHCP server %s is one of %d servers answering on interface %s", server, count, ifaceName))
+		result = result.warn(fmt.Errorf("%d DHCP servers answered - competing servers may take over PXE boots", count))
+		result.ErrorClass = "rogue-server"
+		return result
+	}
+
+	return result.pass()
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"net"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv4/nclient4"
+)
+
+// openDHCPProbeConn opens a raw socket that receives the offers whatever the configuration of the interface.
+func openDHCPProbeConn(ifaceName string) (net.PacketConn, error) {
+	return nclient4.NewRawUDPConn(ifaceName, dhcpv4.ClientPort)
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"net"
+)
+
+func openDHCPProbeConn(ifaceName string) (net.PacketConn, error) {
+	return nil, errors.New("the DHCP probe is implemented for Linux systems only")
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"golang.org/x/net/ipv4"
+)
+
+// Offers not followed by a request are released after this delay
+const DHCP_OFFER_TIMEOUT = time.Minute
+
+// dhcpLease is an address offered or acknowledged to a client.
+type dhcpLease struct {
+	ip       netip.Addr
+	expires  time.Time
+	hostname string
+}
+
+// dhcpResponder answers DISCOVER and REQUEST messages with addresses of a scratch pool and the PXE boot parameters,
+// so a test server can complete the DHCP handshake with the site controller - directly or through a DHCP relay.
+type dhcpResponder struct {
+	mu       sync.Mutex
+	serverIP netip.Addr
+	// Source address of the replies - the server listens on all the addresses of the interface
+	sourceIP   netip.Addr
+	poolStart  netip.Addr
+	poolEnd    netip.Addr
+	netmask    net.IPMask
+	router     netip.Addr
+	leaseTime  time.Duration
+	nextServer netip.Addr
+	bootFile   string
+	// Leases by client hardware address
+	leases map[string]*dhcpLease
+}
+
+// parseDHCPPool parses an address range in the form <first>-<last>.
+func parseDHCPPool(pool string) (netip.Addr, netip.Addr, error) {
+	first, last, found := strings.Cut(pool, "-")
+	if !found {
+		return netip.Addr{}, netip.Addr{}, errors.New("expected <first>-<last>")
+	}
+
+	start, err := netip.ParseAddr(strings.TrimSpace(first))
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, err
+	}
+	end, err := netip.ParseAddr(strings.TrimSpace(last))
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, err
+	}
+
+	if !start.Is4() || !end.Is4() {
+		return netip.Addr{}, netip.Addr{}, errors.New("only IPv4 addresses are supported")
+	}
+	if end.Less(start) {
+		return netip.Addr{}, netip.Addr{}, errors.New("the last address is lower than the first address")
+	}
+
+	return start, end, nil
+}
+
+// newDHCPResponder configures the responder from the dhcp-* arguments of site-service.
+// The server identifier is the listen-ip, or the next-server when listening on all interfaces.
+func newDHCPResponder(listenIP netip.Addr, args map[string]string) (*dhcpResponder, error) {
+	responder := &dhcpResponder{
+		bootFile: args["dhcp-bootfile"],
+		leases:   map[string]*dhcpLease{},
+	}
+
+	var err error
+	responder.poolStart, responder.poolEnd, err = parseDHCPPool(args["dhcp-pool"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid dhcp-pool argument (%s) - %s", args["dhcp-pool"], err.Error())
+	}
+
+	netmask, err := netip.ParseAddr(args["dhcp-netmask"])
+	if err != nil || !netmask.Is4() {
+		return nil, fmt.Errorf("invalid dhcp-netmask argument (%s)", args["dhcp-netmask"])
+	}
+	responder.netmask = net.IPMask(netmask.AsSlice())
+
+	if router := args["dhcp-router"]; router != "" {
+		responder.router, err = netip.ParseAddr(router)
+		if err != nil || !responder.router.Is4() {
+			return nil, fmt.Errorf("invalid dhcp-router argument (%s)", router)
+		}
+	}
+
+	if nextServer := args["dhcp-next-server"]; nextServer != "" {
+		responder.nextServer, err = netip.ParseAddr(nextServer)
+		if err != nil || !responder.nextServer.Is4() {
+			return nil, fmt.Errorf("invalid dhcp-next-server argument (%s)", nextServer)
+		}
+	}
+
+	responder.leaseTime, err = time.ParseDuration(args["dhcp-lease-time"])
+	if err != nil || responder.leaseTime < time.Minute {
+		return nil, fmt.Errorf("invalid dhcp-lease-time argument (%s) - expected a duration of at least 1m", args["dhcp-lease-time"])
+	}
+
+	responder.serverIP = listenIP
+	if listenIP.IsUnspecified() {
+		responder.serverIP = responder.nextServer
+	} else {
+		responder.sourceIP = listenIP
+	}
+	if !responder.serverIP.Is4() {
+		return nil, errors.New("the DHCP server identifier is taken from listen-ip or dhcp-next-server - set one of them to an IPv4 address of this host")
+	}
+
+	return responder, nil
+}
+
+func (responder *dhcpResponder) inPool(ip netip.Addr) bool {
+	return ip.IsValid() && !ip.Less(responder.poolStart) && !responder.poolEnd.Less(ip)
+}
+
+// allocate returns the address leased to the client, the requested address if it is free, or the first free address.
+func (responder *dhcpResponder) allocate(mac string, requested netip.Addr) (netip.Addr, error) {
+	now := time.Now()
+
+	used := map[netip.Addr]bool{}
+	for clientMAC, lease := range responder.leases {
+		if lease.expires.Before(now) {
+			delete(responder.leases, clientMAC)
+			continue
+		}
+		if clientMAC != mac {
+			used[lease.ip] = true
+		}
+	}
+
+	if lease, ok := responder.leases[mac]; ok {
+		return lease.ip, nil
+	}
+
+	if responder.inPool(requested) && !used[requested] {
+		return requested, nil
+	}
+
+	for ip := responder.poolStart; responder.inPool(ip); ip = ip.Next() {
+		if !used[ip] {
+			return ip, nil
+		}
+	}
+
+	return netip.Addr{}, errors.New("no free address in the pool")
+}
+
+// handle implements server4.Handler.
+func (responder *dhcpResponder) handle(conn net.PacketConn, peer net.Addr, m *dhcpv4.DHCPv4) {
+	slog.Info(fmt.Sprintf("Received DHCP packet from %s - %s", peer.String(), m.Summary()))
+
+	if m.OpCode != dhcpv4.OpcodeBootRequest {
+		return
+	}
+
+	var reply *dhcpv4.DHCPv4
+	var err error
+
+	switch m.MessageType() {
+	case dhcpv4.MessageTypeDiscover:
+		reply, err = responder.offer(m)
+	case dhcpv4.MessageTypeRequest:
+		reply, err = responder.acknowledge(m)
+	case dhcpv4.MessageTypeRelease, dhcpv4.MessageTypeDecline:
+		responder.release(m)
+		return
+	default:
+		return
+	}
+	if err != nil {
+		slog.Error(fmt.Sprintf("Could not answer DHCP %s from %s - %s", m.MessageType(), m.ClientHWAddr, err.Error()))
+		return
+	}
+	if reply == nil {
+		return
+	}
+
+	destination := responder.replyAddress(m, reply)
+	if err := responder.send(conn, reply.ToBytes(), destination); err != nil {
+		slog.Error(fmt.Sprintf("Error sending DHCP %s to %s - %s", reply.MessageType(), destination, err.Error()))
+		return
+	}
+
+	slog.Info(fmt.Sprintf("Sent DHCP %s to %s via %s - %s", reply.MessageType(), m.ClientHWAddr, destination, reply.Summary()))
+}
+
+func (responder *dhcpResponder) offer(m *dhcpv4.DHCPv4) (*dhcpv4.DHCPv4, error) {
+	responder.mu.Lock()
+	defer responder.mu.Unlock()
+
+	requested, _ := netip.AddrFromSlice(m.RequestedIPAddress().To4())
+	ip, err := responder.allocate(m.ClientHWAddr.String(), requested)
+	if err != nil {
+		return nil, err
+	}
+
+	lease, ok := responder.leases[m.ClientHWAddr.String()]
+	if !ok {
+		lease = &dhcpLease{ip: ip, expires: time.Now().Add(DHCP_OFFER_TIMEOUT)}
+		responder.leases[m.ClientHWAddr.String()] = lease
+	}
+	lease.hostname = m.HostName()
+
+	return responder.reply(m, dhcpv4.MessageTypeOffer, ip)
+}
+
+func (responder *dhcpResponder) acknowledge(m *dhcpv4.DHCPv4) (*dhcpv4.DHCPv4, error) {
+	responder.mu.Lock()
+	defer responder.mu.Unlock()
+
+	// The client selected the offer of another server
+	if serverID := m.ServerIdentifier(); serverID != nil && !serverID.Equal(net.IP(responder.serverIP.AsSlice())) {
+		slog.Info(fmt.Sprintf("DHCP client %s selected server %s", m.ClientHWAddr, serverID))
+		delete(responder.leases, m.ClientHWAddr.String())
+		return nil, nil
+	}
+
+	// SELECTING and INIT-REBOOT clients send the requested address option, RENEWING and REBINDING clients the client address
+	requested, _ := netip.AddrFromSlice(m.RequestedIPAddress().To4())
+	if !requested.IsValid() {
+		requested, _ = netip.AddrFromSlice(m.ClientIPAddr.To4())
+	}
+
+	lease, ok := responder.leases[m.ClientHWAddr.String()]
+	if !ok || lease.ip != requested || lease.expires.Before(time.Now()) {
+		// An unknown client in INIT-REBOOT state gets the address if it is free
+		ip, err := responder.allocate(m.ClientHWAddr.String(), requested)
+		if err != nil || ip != requested {
+			slog.Info(fmt.Sprintf("DHCP client %s requested %s which is not leased to it", m.ClientHWAddr, requested))
+			return responder.reply(m, dhcpv4.MessageTypeNak, netip.Addr{})
+		}
+		lease = &dhcpLease{ip: ip}
+		responder.leases[m.ClientHWAddr.String()] = lease
+	}
+
+	lease.expires = time.Now().Add(responder.leaseTime)
+	lease.hostname = m.HostName()
+
+	slog.Info(fmt.Sprintf("DHCP lease of %s to %s (%s) until %s", lease.ip, m.ClientHWAddr, lease.hostname, lease.expires.Format(time.RFC3339)))
+
+	return responder.reply(m, dhcpv4.MessageTypeAck, lease.ip)
+}
+
+func (responder *dhcpResponder) release(m *dhcpv4.DHCPv4) {
+	responder.mu.Lock()
+	defer responder.mu.Unlock()
+
+	if lease, ok := responder.leases[m.ClientHWAddr.String()]; ok {
+		slog.Info(fmt.Sprintf("DHCP client %s released %s - %s", m.ClientHWAddr, lease.ip, m.MessageType()))
+		delete(responder.leases, m.ClientHWAddr.String())
+	}
+}
+
+// reply builds an OFFER, ACK or NAK with the lease parameters and the boot server options.
+func (responder *dhcpResponder) reply(m *dhcpv4.DHCPv4, messageType dhcpv4.MessageType, ip netip.Addr) (*dhcpv4.DHCPv4, error) {
+	modifiers := []dhcpv4.Modifier{
+		dhcpv4.WithMessageType(messageType),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(responder.serverIP.AsSlice())),
+	}
+
+	if messageType != dhcpv4.MessageTypeNak {
+		modifiers = append(modifiers,
+			dhcpv4.WithYourIP(ip.AsSlice()),
+			dhcpv4.WithNetmask(responder.netmask),
+			dhcpv4.WithLeaseTime(uint32(responder.leaseTime.Seconds())),
+		)
+		if responder.router.IsValid() {
+			modifiers = append(modifiers, dhcpv4.WithRouter(responder.router.AsSlice()))
+		}
+		// PXE clients read the boot server from siaddr and the file field, other clients from options 66 and 67
+		if responder.nextServer.IsValid() {
+			modifiers = append(modifiers,
+				dhcpv4.WithServerIP(responder.nextServer.AsSlice()),
+				dhcpv4.WithOption(dhcpv4.OptTFTPServerName(responder.nextServer.String())),
+			)
+		}
+		if responder.bootFile != "" {
+			modifiers = append(modifiers, dhcpv4.WithOption(dhcpv4.OptBootFileName(responder.bootFile)))
+		}
+	}
+
+	reply, err := dhcpv4.NewReplyFromRequest(m, modifiers...)
+	if err != nil {
+		return nil, err
+	}
+	if messageType != dhcpv4.MessageTypeNak {
+		reply.BootFileName = responder.bootFile
+	}
+
+	return reply, nil
+}
+
+// send writes the reply from the listen-ip address when it is set.
+func (responder *dhcpResponder) send(conn net.PacketConn, packet []byte, destination net.Addr) error {
+	udpConn, ok := conn.(*net.UDPConn)
+	if !ok || !responder.sourceIP.IsValid() {
+		_, err := conn.WriteTo(packet, destination)
+		return err
+	}
+
+	_, err := ipv4.NewPacketConn(udpConn).WriteTo(packet, &ipv4.ControlMessage{Src: net.IP(responder.sourceIP.AsSlice())}, destination)
+	return err
+}
+
+// replyAddress follows RFC 2131 4.1 - replies go to the relay agent, to the address of a configured client
+// or are broadcast, as the client does not have an address yet.
+func (responder *dhcpResponder) replyAddress(m *dhcpv4.DHCPv4, reply *dhcpv4.DHCPv4) net.Addr {
+	if !m.GatewayIPAddr.IsUnspecified() && m.GatewayIPAddr != nil {
+		return &net.UDPAddr{IP: m.GatewayIPAddr, Port: dhcpv4.ServerPort}
+	}
+	if !m.ClientIPAddr.IsUnspecified() && m.ClientIPAddr != nil && reply.MessageType() != dhcpv4.MessageTypeNak {
+		return &net.UDPAddr{IP: m.ClientIPAddr, Port: dhcpv4.ClientPort}
+	}
+
+	return &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpv4.ClientPort}
+}
@@ -12,41 +12,78 @@ import (
 	"github.com/insomniacslk/dhcp/dhcpv4/server4"
 )
 
-func (app *application) startDHCPServer(ctx context.Context, ip netip.Addr, port uint16) {
+// startDHCPServer listens on all addresses, as a socket bound to a unicast address does not receive the broadcasts
+// of the clients. With a listen IP the server only listens on the interface that owns it.
+func (app *application) startDHCPServer(ctx context.Context, ip netip.Addr, port uint16, handler server4.Handler) {
 	defer app.wg.Done()
 
 	address := &net.UDPAddr{
-		IP:   net.ParseIP(ip.String()),
+		IP:   net.IPv4zero,
 		Port: int(port),
 	}
 
-	slog.Info(fmt.Sprintf("Starting DHCP server on %s", address))
+	name := address.String()
+	ifname := ""
+	if !ip.IsUnspecified() {
+		iface, err := interfaceByAddr(ip)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error starting DHCP server on %s - %s", ip, err.Error()))
+			return
+		}
+		ifname = iface.Name
+		name = fmt.Sprintf("%s of interface %s", address, ifname)
+	}
+
+	slog.Info(fmt.Sprintf("Starting DHCP server on %s", name))
 
-	srv, err := server4.NewServer("", address, dhcpHandler)
+	srv, err := server4.NewServer(ifname, address, handler)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error starting DHCP server on %s - %s", address, err.Error()))
+		slog.Error(fmt.Sprintf("Error starting DHCP server on %s - %s", name, err.Error()))
 		return
 	}
 
 	go func() {
 		<-ctx.Done()
 
-		slog.Info(fmt.Sprintf("Shutting down DHCP server on %s", address))
+		slog.Info(fmt.Sprintf("Shutting down DHCP server on %s", name))
 
 		if err := srv.Close(); err != nil {
-			slog.Error(fmt.Sprintf("Error shutting down DHCP server on %s - %s", address, err.Error()))
+			slog.Error(fmt.Sprintf("Error shutting down DHCP server on %s - %s", name, err.Error()))
 		}
 	}()
 
 	err = srv.Serve()
 	if !errors.Is(err, net.ErrClosed) {
-		slog.Error(fmt.Sprintf("Error starting DHCP server on %s - %s", address, err.Error()))
+		slog.Error(fmt.Sprintf("Error starting DHCP server on %s - %s", name, err.Error()))
 		return
 	}
 
-	slog.Info(fmt.Sprintf("DHCP server on %s shut down", address))
+	slog.Info(fmt.Sprintf("DHCP server on %s shut down", name))
 }
 
+// dhcpHandler only logs the received packets.
 func dhcpHandler(conn net.PacketConn, peer net.Addr, m *dhcpv4.DHCPv4) {
 	slog.Info(fmt.Sprintf("Received DHCP packet from %s - %s", peer.String(), m.Summary()))
 }
+
+// interfaceByAddr returns the network interface that owns the IP address.
+func interfaceByAddr(ip netip.Addr) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if prefix, err := netip.ParsePrefix(addr.String()); err == nil && prefix.Addr().Unmap() == ip.Unmap() {
+				return &iface, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no network interface has the address %s", ip)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed policies/firmware.yaml
+var defaultFirmwarePolicy []byte
+
+// firmwarePolicy lists the minimum firmware versions supported by MetalSoft.
+type firmwarePolicy struct {
+	Rules []firmwareRule `yaml:"rules"`
+}
+
+type firmwareRule struct {
+	Name   string `yaml:"name"`
+	Vendor string `yaml:"vendor"`
+	// Regular expressions matched against the model of the manager and of the system
+	BMCModel    string              `yaml:"bmc-model"`
+	SystemModel string              `yaml:"system-model"`
+	BMCFirmware firmwareRequirement `yaml:"bmc-firmware"`
+	BIOS        firmwareRequirement `yaml:"bios"`
+
+	bmcModelRegexp    *regexp.Regexp
+	systemModelRegexp *regexp.Regexp
+}
+
+type firmwareRequirement struct {
+	Minimum     string `yaml:"minimum"`
+	Recommended string `yaml:"recommended"`
+}
+
+var firmwareVersionRegexp = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// loadFirmwarePolicy reads the embedded policy and merges the rules of the override file into it.
+// Override rules replace the embedded rules with the same name.
+func loadFirmwarePolicy(fileName string) (*firmwarePolicy, error) {
+	policy, err := parseFirmwarePolicy(defaultFirmwarePolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	if fileName == "" {
+		return policy, nil
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	override, err := parseFirmwarePolicy(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range override.Rules {
+		policy.Rules = slices.DeleteFunc(policy.Rules, func(existing firmwareRule) bool {
+			return strings.EqualFold(existing.Name, rule.Name)
+		})
+	}
+	policy.Rules = append(override.Rules, policy.Rules...)
+
+	return policy, nil
+}
+
+func parseFirmwarePolicy(data []byte) (*firmwarePolicy, error) {
+	policy := &firmwarePolicy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("could not parse firmware policy - %s", err.Error())
+	}
+
+	var err error
+	for index := range policy.Rules {
+		rule := &policy.Rules[index]
+		if rule.Name == "" || rule.Vendor == "" {
+			return nil, fmt.Errorf("firmware policy rule %d must have a name and a vendor", index+1)
+		}
+		if rule.bmcModelRegexp, err = regexp.Compile("(?i)" + rule.BMCModel); err != nil {
+			return nil, fmt.Errorf("firmware policy rule %s has invalid bmc-model - %s", rule.Name, err.Error())
+		}
+		if rule.systemModelRegexp, err = regexp.Compile("(?i)" + rule.SystemModel); err != nil {
+			return nil, fmt.Errorf("firmware policy rule %s has invalid system-model - %s", rule.Name, err.Error())
+		}
+		for _, version := range []string{rule.BMCFirmware.Minimum, rule.BMCFirmware.Recommended, rule.BIOS.Minimum, rule.BIOS.Recommended} {
+			if version != "" && !firmwareVersionRegexp.MatchString(version) {
+				return nil, fmt.Errorf("firmware policy rule %s has invalid version '%s'", rule.Name, version)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+func (rule firmwareRule) matches(inventory redfishInventory) bool {
+	vendor := strings.ToLower(rule.Vendor)
+	if !strings.HasPrefix(strings.ToLower(inventory.Vendor), vendor) && !strings.HasPrefix(strings.ToLower(inventory.Manufacturer), vendor) {
+		return false
+	}
+
+	return rule.bmcModelRegexp.MatchString(inventory.BMCModel) && rule.systemModelRegexp.MatchString(inventory.Model)
+}
+
+// evaluate checks the firmware of the server against the first matching rule and returns the name of the rule,
+// the versions below the minimum and the versions below the recommended ones.
+func (policy *firmwarePolicy) evaluate(inventory redfishInventory) (string, []string, []string) {
+	problems := []string{}
+	warnings := []string{}
+
+	index := slices.IndexFunc(policy.Rules, func(rule firmwareRule) bool {
+		return rule.matches(inventory)
+	})
+	if index < 0 {
+		warnings = append(warnings, fmt.Sprintf("no firmware policy for %s %s with BMC %s", inventory.Manufacturer, inventory.Model, inventory.BMCModel))
+		return "", problems, warnings
+	}
+	rule := policy.Rules[index]
+
+	for _, component := range []struct {
+		name        string
+		version     string
+		requirement firmwareRequirement
+	}{
+		{"BMC firmware", inventory.BMCFirmware, rule.BMCFirmware},
+		{"BIOS", inventory.BIOSVersion, rule.BIOS},
+	} {
+		if component.requirement.Minimum == "" && component.requirement.Recommended == "" {
+			continue
+		}
+		if component.version == "" {
+			problems = append(problems, fmt.Sprintf("%s version is not reported", component.name))
+			continue
+		}
+		if !firmwareVersionRegexp.MatchString(component.version) {
+			warnings = append(warnings, fmt.Sprintf("%s version '%s' could not be compared", component.name, component.version))
+			continue
+		}
+
+		if component.requirement.Minimum != "" && compareFirmwareVersions(component.version, component.requirement.Minimum) < 0 {
+			problems = append(problems, fmt.Sprintf("%s %s is below the minimum %s supported for %s", component.name, component.version, component.requirement.Minimum, rule.Name))
+		} else if component.requirement.Recommended != "" && compareFirmwareVersions(component.version, component.requirement.Recommended) < 0 {
+			warnings = append(warnings, fmt.Sprintf("%s %s is below the version %s recommended for %s", component.name, component.version, component.requirement.Recommended, rule.Name))
+		}
+	}
+
+	return rule.Name, problems, warnings
+}
+
+// compareFirmwareVersions compares the first dotted version number found in each string,
+// as vendors decorate versions differently (e.g. "iLO 5 v2.72", "U46 v2.72 (03/14/2023)", "TEI3B0N-5.30").
+func compareFirmwareVersions(a string, b string) int {
+	aParts := strings.Split(firmwareVersionRegexp.FindString(a), ".")
+	bParts := strings.Split(firmwareVersionRegexp.FindString(b), ".")
+
+	for index := 0; index < max(len(aParts), len(bParts)); index++ {
+		aPart, bPart := 0, 0
+		if index < len(aParts) {
+			aPart, _ = strconv.Atoi(aParts[index])
+		}
+		if index < len(bParts) {
+			bPart, _ = strconv.Atoi(bParts[index])
+		}
+		if aPart != bPart {
+			return aPart - bPart
+		}
+	}
+
+	return 0
+}
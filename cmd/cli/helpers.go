@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -24,115 +28,122 @@ import (
 	"golang.org/x/net/ipv4"
 )
 
-const TIMEOUT = 10 * time.Second
-
-func (app *application) testHTTPConnection(ctx context.Context, host string, port int) int {
+func (app *application) testHTTPConnection(ctx context.Context, host string, port int) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing HTTP connection to %s:%d", host, port))
 
-	client := &http.Client{}
-	client.Timeout = TIMEOUT
+	result := newCheckResult("HTTP", host, port)
+
+	client := app.timeouts(ctx).httpClient(false)
 
 	response, err := client.Get("http://" + net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for HTTP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer response.Body.Close()
 
 	slog.Debug(fmt.Sprintf("HTTP GET %s:%d returned status %s", host, port, response.Status))
+	result.addFact("status", response.Status)
 
-	return 0
+	return result.pass()
 }
 
-func (app *application) testHTTPSConnection(ctx context.Context, host string, port int) int {
+func (app *application) testHTTPSConnection(ctx context.Context, host string, port int) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing HTTPS connection to %s:%d", host, port))
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
+	result := newCheckResult("HTTPS", host, port)
 
-	client := &http.Client{Transport: tr}
-	client.Timeout = TIMEOUT
+	client := app.timeouts(ctx).httpClient(true)
 
 	response, err := client.Get("https://" + net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for HTTPS connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer response.Body.Close()
 
 	slog.Debug(fmt.Sprintf("HTTPS GET %s:%d returned status %s", host, port, response.Status))
+	result.addFact("status", response.Status)
 
-	return 0
+	return result.pass()
 }
 
-func (app *application) testLink(ctx context.Context, url string) int {
+func (app *application) testLink(ctx context.Context, url string) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing connection to %s", url))
 
-	client := &http.Client{}
-	client.Timeout = TIMEOUT
+	result := newLinkCheckResult(url)
+
+	client := app.timeouts(ctx).httpClient(false)
 
 	response, err := client.Get(url)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for link %s - %s", url, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer response.Body.Close()
 
 	slog.Debug(fmt.Sprintf("GET %s returned status %s", url, response.Status))
+	result.addFact("status", response.Status)
 
-	return 0
+	return result.pass()
 }
 
-func (app *application) testTCPConnection(ctx context.Context, host string, port int) int {
+func (app *application) testTCPConnection(ctx context.Context, host string, port int) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing TCP connection to %s:%d", host, port))
 
-	conn, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	result := newCheckResult("TCP", host, port)
+	timeouts := app.timeouts(ctx)
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeouts.Connect)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer conn.Close()
 
-	err = conn.SetWriteDeadline(time.Now().Add(TIMEOUT))
+	err = conn.SetWriteDeadline(time.Now().Add(timeouts.Read))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	dataIn := []byte("PING")
 	bytesWritten, err := conn.Write(dataIn)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	slog.Debug(fmt.Sprintf("Wrote %d bytes to TCP %s:%d - %s", bytesWritten, host, port, string(dataIn)))
 
-	err = conn.SetReadDeadline(time.Now().Add(TIMEOUT))
+	err = conn.SetReadDeadline(time.Now().Add(timeouts.Read))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	dataOut := make([]byte, 1024)
 	bytesRead, err := conn.Read(dataOut)
 	if err != nil {
 		slog.Warn(fmt.Sprintf("Could not read from TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 0
+		// Services that do not answer the probe are reachable all the same
+		result.addFact("response", "none")
+		return result.pass()
 	}
 
 	slog.Debug(fmt.Sprintf("Read %d bytes from TCP %s:%d - %s", bytesRead, host, port, string(dataOut[:bytesRead])))
 
-	return 0
+	return result.pass()
 }
 
-func (app *application) testEncryptedTCPConnection(ctx context.Context, host string, port int) int {
+func (app *application) testEncryptedTCPConnection(ctx context.Context, host string, port int) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing encrypted TCP connection to %s:%d", host, port))
 
+	result := newCheckResult("TLS", host, port)
+
 	caCertPool := x509.NewCertPool()
 	ok := caCertPool.AppendCertsFromPEM(getCACertificate())
 	if !ok {
 		slog.Error("Failed to load client certificate")
-		return 1
+		return result.fail(errors.New("failed to load client certificate"))
 	}
 
 	cfg := &tls.Config{
@@ -140,55 +151,61 @@ func (app *application) testEncryptedTCPConnection(ctx context.Context, host str
 		ServerName: "localhost",
 	}
 
+	timeouts := app.timeouts(ctx)
 	dialer := &net.Dialer{
-		Timeout: 30 * time.Second,
+		Timeout: timeouts.Connect,
 	}
 
 	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)), cfg)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer conn.Close()
 
-	err = conn.SetWriteDeadline(time.Now().Add(TIMEOUT))
+	err = conn.SetWriteDeadline(time.Now().Add(timeouts.Read))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	dataIn := []byte("T12345678901234567890123456789012345")
 	bytesWritten, err := conn.Write(dataIn)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	slog.Debug(fmt.Sprintf("Wrote %d bytes to TCP %s:%d - %s", bytesWritten, host, port, string(dataIn)))
 
-	err = conn.SetReadDeadline(time.Now().Add(TIMEOUT))
+	err = conn.SetReadDeadline(time.Now().Add(timeouts.Read))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	dataOut := make([]byte, 1024)
 	bytesRead, err := conn.Read(dataOut)
 	if err != nil && err.Error() != "EOF" {
 		slog.Warn(fmt.Sprintf("Could not read from TCP connection to %s:%d - %s", host, port, err.Error()))
-		return 0
+		// Services that do not answer the probe are reachable all the same
+		result.addFact("response", "none")
+		return result.pass()
 	}
 
 	slog.Debug(fmt.Sprintf("Read %d bytes from TCP %s:%d - %s", bytesRead, host, port, string(dataOut[:bytesRead])))
 
-	return 0
+	return result.pass()
 }
 
-func (app *application) testUDPConnection(ctx context.Context, host string, port int, kind string) int {
+func (app *application) testUDPConnection(ctx context.Context, host string, port int, kind string) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing UDP connection to %s:%d", host, port))
 
+	result := newCheckResult("UDP", host, port)
+	timeouts := app.timeouts(ctx)
+
 	conn, err := net.Dial("udp", net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for UDP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer conn.Close()
 
@@ -200,96 +217,260 @@ func (app *application) testUDPConnection(ctx context.Context, host string, port
 		// Generic UDP ping
 		dataIn = []byte("PING")
 	}
-	err = conn.SetWriteDeadline(time.Now().Add(TIMEOUT))
+	err = conn.SetWriteDeadline(time.Now().Add(timeouts.Read))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for UDP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	bytesWritten, err := conn.Write(dataIn)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for UDP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	slog.Debug(fmt.Sprintf("Wrote %d bytes to UDP %s:%d - %X", bytesWritten, host, port, dataIn))
 
 	dataOut := make([]byte, 1024)
-	err = conn.SetReadDeadline(time.Now().Add(TIMEOUT))
+	err = conn.SetReadDeadline(time.Now().Add(timeouts.Read))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for UDP connection to %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	bytesRead, err := conn.Read(dataOut)
 	if err != nil {
 		slog.Warn(fmt.Sprintf("Could not read from UDP connection to %s:%d - %s", host, port, err.Error()))
-		return 0
+		// Services that do not answer the probe are reachable all the same
+		result.addFact("response", "none")
+		return result.pass()
 	}
 
 	slog.Debug(fmt.Sprintf("Read %d bytes from UDP %s:%d - %X", bytesRead, host, port, dataOut[:bytesRead]))
 
-	return 0
+	return result.pass()
+}
+
+// testTFTPTransfer reads a file over TFTP and reports the negotiated block size and the throughput.
+// The embedded test file of the site-service command is also compared with the local copy.
+func (app *application) testTFTPTransfer(ctx context.Context, host string, port int, filename string) CheckResult {
+	slog.Debug(fmt.Sprintf("Testing TFTP transfer of %s from %s:%d", filename, host, port))
+
+	result := newCheckResult("TFTP", host, port)
+	timeouts := app.timeouts(ctx)
+
+	server, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to resolve TFTP server %s:%d - %s", host, port, err.Error()))
+		return result.fail(err)
+	}
+
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed test for TFTP transfer from %s:%d - %s", host, port, err.Error()))
+		return result.fail(err)
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	request := tftpRequest{
+		opcode:   TFTP_OPCODE_RRQ,
+		filename: filename,
+		mode:     "octet",
+		options: map[string]string{
+			"blksize": strconv.Itoa(TFTP_PROBE_BLOCK_SIZE),
+			"tsize":   "0",
+		},
+	}
+
+	start := time.Now()
+
+	// The server answers from a new port, the transfer port, which the replies must come from
+	var peer *net.UDPAddr
+	packet, dest, wait := request.marshal(), server, timeouts.Connect
+	blockSize, size, block := TFTP_DEFAULT_BLOCK_SIZE, -1, uint16(1)
+	var data []byte
+
+	for {
+		reply, from, err := tftpReceive(conn, packet, dest, server, peer, wait)
+		if err != nil {
+			if peer == nil {
+				slog.Error(fmt.Sprintf("No answer to TFTP read request of %s from %s:%d - %s", filename, host, port, err.Error()))
+				return result.fail(fmt.Errorf("no answer to the read request - %s", err.Error()))
+			}
+			slog.Error(fmt.Sprintf("TFTP transfer of %s from %s:%d stopped after %d bytes - %s", filename, host, port, len(data), err.Error()))
+			return result.fail(fmt.Errorf("transfer from port %d stopped after %d bytes - %s", peer.Port, len(data), err.Error()))
+		}
+
+		if peer == nil {
+			peer, dest, wait = from, from, timeouts.Read
+			result.addFact("TransferPort", strconv.Itoa(peer.Port))
+		}
+
+		switch binary.BigEndian.Uint16(reply) {
+		case TFTP_OPCODE_ERROR:
+			err := parseTFTPError(reply)
+			slog.Error(fmt.Sprintf("TFTP server %s:%d refused the transfer of %s - %s", host, port, filename, err.Error()))
+			return result.fail(err)
+
+		case TFTP_OPCODE_OACK:
+			if block != 1 || len(data) > 0 {
+				continue
+			}
+			options := parseTFTPOptions(reply)
+			if value, err := strconv.Atoi(options["blksize"]); err == nil {
+				blockSize = value
+			}
+			if value, err := strconv.Atoi(options["tsize"]); err == nil {
+				size = value
+			}
+			slog.Debug(fmt.Sprintf("TFTP server %s:%d acknowledged options %v", host, port, options))
+			packet = tftpAckPacket(0)
+
+		case TFTP_OPCODE_DATA:
+			// A repeated block means the acknowledgement was lost and is sent again
+			if len(reply) < 4 || binary.BigEndian.Uint16(reply[2:]) != block {
+				continue
+			}
+			data = append(data, reply[4:]...)
+			packet = tftpAckPacket(block)
+			if len(reply)-4 < blockSize {
+				// The server does not acknowledge the last acknowledgement
+				conn.WriteToUDP(packet, peer)
+				return tftpTransferResult(result, filename, data, size, blockSize, int(block), time.Since(start))
+			}
+			block++
+		}
+	}
 }
 
-var sshPassword string
+// tftpReceive sends the packet and waits for the next packet of the transfer, repeating the packet every TFTP_TIMEOUT.
+// Before the transfer port is known any port of the server is accepted.
+func tftpReceive(conn *net.UDPConn, packet []byte, dest *net.UDPAddr, server *net.UDPAddr, peer *net.UDPAddr, wait time.Duration) ([]byte, *net.UDPAddr, error) {
+	buffer := make([]byte, TFTP_MAX_BLOCK_SIZE+4)
+	deadline := time.Now().Add(wait)
+
+	for time.Now().Before(deadline) {
+		if _, err := conn.WriteToUDP(packet, dest); err != nil {
+			return nil, nil, err
+		}
+
+		if err := conn.SetReadDeadline(time.Now().Add(min(TFTP_TIMEOUT, time.Until(deadline)))); err != nil {
+			return nil, nil, err
+		}
 
-func sshInteractive(user, instruction string, questions []string, echos []bool) (answers []string, err error) {
-	answers = make([]string, len(questions))
-	for n := range questions {
-		answers[n] = sshPassword
+		for {
+			bytesRead, from, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return nil, nil, err
+			}
+
+			if bytesRead < 4 || !from.IP.Equal(server.IP) || (peer != nil && from.Port != peer.Port) {
+				continue
+			}
+
+			return buffer[:bytesRead], from, nil
+		}
 	}
 
-	return answers, nil
+	return nil, nil, fmt.Errorf("no packet received in %s", wait)
 }
 
-func (app *application) testSSHConnection(ctx context.Context, host string, port int, username string, password string) int {
-	slog.Debug(fmt.Sprintf("Testing SSH connection to %s:%d", host, port))
+// tftpTransferResult validates the received file and reports the transfer.
+func tftpTransferResult(result CheckResult, filename string, data []byte, size int, blockSize int, blocks int, elapsed time.Duration) CheckResult {
+	checksum := sha256.Sum256(data)
 
-	sshPassword = password
+	result.addFact("Size", strconv.Itoa(len(data)))
+	result.addFact("BlockSize", strconv.Itoa(blockSize))
+	result.addFact("Blocks", strconv.Itoa(blocks))
+	result.addFact("SHA256", hex.EncodeToString(checksum[:]))
+	result.addFact("Throughput", fmt.Sprintf("%.1f KiB/s", float64(len(data))/1024/max(elapsed.Seconds(), 0.001)))
+
+	slog.Debug(fmt.Sprintf("Received %d bytes of %s over TFTP in %d blocks of %d bytes in %s", len(data), filename, blocks, blockSize, elapsed))
+
+	if size >= 0 && size != len(data) {
+		slog.Error(fmt.Sprintf("TFTP transfer of %s received %d bytes while the server announced %d", filename, len(data), size))
+		return result.fail(fmt.Errorf("received %d bytes while the server announced %d", len(data), size))
+	}
 
-	config := &ssh.ClientConfig{
+	if strings.TrimPrefix(filename, "/") == TFTP_TEST_FILE && checksum != sha256.Sum256(tftpTestPayload) {
+		slog.Error(fmt.Sprintf("TFTP transfer of %s does not match the embedded test file", filename))
+		return result.fail(errors.New("the received file does not match the test file"))
+	}
+
+	return result.pass()
+}
+
+// sshInteractive answers all keyboard-interactive questions with the password.
+func sshInteractive(password string) ssh.KeyboardInteractiveChallenge {
+	return func(user, instruction string, questions []string, echos []bool) (answers []string, err error) {
+		answers = make([]string, len(questions))
+		for n := range questions {
+			answers[n] = password
+		}
+
+		return answers, nil
+	}
+}
+
+// sshClientConfig authenticates with keyboard-interactive and password methods and accepts any host key.
+func (app *application) sshClientConfig(ctx context.Context, username string, password string) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
 		User: username,
 		Auth: []ssh.AuthMethod{
-			ssh.KeyboardInteractive(sshInteractive),
+			ssh.KeyboardInteractive(sshInteractive(password)),
 			ssh.Password(password),
 		},
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         TIMEOUT,
+		Timeout:         app.timeouts(ctx).Connect,
 	}
+}
 
-	client, err := ssh.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)), config)
+func (app *application) testSSHConnection(ctx context.Context, host string, port int, username string, password string) CheckResult {
+	slog.Debug(fmt.Sprintf("Testing SSH connection to %s:%d", host, port))
+
+	result := newCheckResult("SSH", host, port)
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)), app.sshClientConfig(ctx, username, password))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Unable to connect to SSH server %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer client.Close()
 
 	session, err := client.NewSession()
 	if err != nil {
 		slog.Error(fmt.Sprintf("Unable to create session with SSH server %s:%d - %s", host, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer session.Close()
 
 	slog.Debug(fmt.Sprintf("Connected to SSH server %s:%d", host, port))
 
-	return 0
+	return result.pass()
 }
 
-func (app *application) testICMPConnection(ctx context.Context, host string) int {
+func (app *application) testICMPConnection(ctx context.Context, host string) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing ICMP connection to %s", host))
 
+	result := newCheckResult("ICMP", host, 0)
+
 	// Listen for ICMP packets
 	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for ICMP connection to %s - %s", host, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer conn.Close()
 
-	err = conn.SetDeadline(time.Now().Add(TIMEOUT))
+	err = conn.SetDeadline(time.Now().Add(app.timeouts(ctx).total()))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed set deadline for ICMP connection to %s - %s", host, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	// Create an ICMP echo request
@@ -305,13 +486,13 @@ func (app *application) testICMPConnection(ctx context.Context, host string) int
 	request, err := message.Marshal(nil)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed marshal request for ICMP connection to %s - %s", host, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	// Send the request
 	if _, err := conn.WriteTo(request, &net.IPAddr{IP: net.ParseIP(host)}); err != nil {
 		slog.Error(fmt.Sprintf("Failed test for ICMP connection to %s - %s", host, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	// Wait for a reply
@@ -319,99 +500,185 @@ func (app *application) testICMPConnection(ctx context.Context, host string) int
 	n, peer, err := conn.ReadFrom(reply)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for ICMP connection to %s - %s", host, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	// Parse the reply
 	rm, err := icmp.ParseMessage(ipv4.ICMPTypeEchoReply.Protocol(), reply[:n])
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for ICMP connection to %s - %s", host, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	switch rm.Type {
 	case ipv4.ICMPTypeTimeExceeded:
 		slog.Debug(fmt.Sprintf("Got ICMP time exceeded from %v", peer))
-		return 1
+		return result.fail(fmt.Errorf("time exceeded from %v", peer))
 	case ipv4.ICMPTypeEchoReply:
 		slog.Debug(fmt.Sprintf("Got ICMP echo reply from %v", peer))
-		return 0
+		return result.pass()
 	default:
 		slog.Debug(fmt.Sprintf("Got ICMP %+v reply from %v - expected echo", rm, peer))
-		return 1
+		return result.fail(fmt.Errorf("unexpected ICMP reply type %v from %v", rm.Type, peer))
 	}
 }
 
-func (app *application) testRedfishAPI(ctx context.Context, hostname string, port int, username string, password string, uri string) (interface{}, error) {
+func (app *application) testRedfishAPI(ctx context.Context, hostname string, port int, username string, password string, uri string) (interface{}, CheckResult) {
 	link := "https://" + hostname + ":" + strconv.Itoa(port) + uri
 	slog.Debug(fmt.Sprintf("Testing Redfish resource %s", link))
 
-	client := resty.New().SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
-	response, err := client.R().
-		SetContext(ctx).
-		SetBasicAuth(username, password).
-		Get(link)
+	result := newCheckResult("Redfish", hostname+uri, port)
+
+	ctx, logout, err := app.redfishLogin(ctx, hostname, port, username, password)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to log in to Redfish on %s:%d - %s", hostname, port, err.Error()))
+		return nil, result.fail(err)
+	}
+	defer logout()
+
+	method, err := app.redfishAuthMethod(ctx, hostname, port, username, password)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to authenticate to Redfish on %s:%d - %s", hostname, port, err.Error()))
+		return nil, result.fail(err)
+	}
+	result.addFact("AuthMethod", method)
+	slog.Debug(fmt.Sprintf("Redfish service on %s:%d accepts %s authentication", hostname, port, method))
+
+	data, err := app.getRedfishResource(ctx, hostname, port, username, password, uri)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for Redfish link %s - %s", link, err.Error()))
+		return nil, result.fail(err)
+	}
+
+	return data, result.pass()
+}
+
+// getRedfishResource fetches and parses a Redfish resource.
+func (app *application) getRedfishResource(ctx context.Context, hostname string, port int, username string, password string, uri string) (interface{}, error) {
+	response, err := app.redfishRequest(ctx, http.MethodGet, hostname, port, username, password, uri, nil)
+	if err != nil {
 		return nil, err
 	}
 
 	if response.StatusCode() != http.StatusOK {
-		slog.Error(fmt.Sprintf("Failed test for Redfish link %s - response status %s", link, response.Status()))
-		return nil, fmt.Errorf("response status %s", response.Status())
+		return nil, redfishStatusError(response)
 	}
 
-	slog.Debug(fmt.Sprintf("Got Redfish response for %s - %s", link, response.Status()))
+	slog.Debug(fmt.Sprintf("Got Redfish response for %s - %s", uri, response.Status()))
 	//slog.Debug(fmt.Sprintf("Response body:\n%s", string(response.Body())))
 
 	// Parse the response JSON
-	var result interface{}
-	err = json.Unmarshal(response.Body(), &result)
+	var data interface{}
+	err = json.Unmarshal(response.Body(), &data)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Could not parse JSON response - %s", err.Error()))
+		slog.Debug(fmt.Sprintf("Could not parse JSON response - %s", err.Error()))
 		return nil, fmt.Errorf("could not parse response")
 	}
 
-	return result, nil
+	return data, nil
 }
 
-func (app *application) testIPMIConnection(ctx context.Context, hostname string, port int, username string, password string) int {
+// redfishRequest sends a request to a Redfish resource and returns the raw response.
+// Requests use the session of the context if there is one, basic authentication if a username is given.
+func (app *application) redfishRequest(ctx context.Context, method string, hostname string, port int, username string, password string, uri string, body interface{}) (*resty.Response, error) {
+	link := "https://" + hostname + ":" + strconv.Itoa(port) + uri
+
+	client := resty.New().
+		SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true}).
+		SetTimeout(app.timeouts(ctx).total())
+	request := client.R().
+		SetContext(ctx)
+	if session := redfishSessionFrom(ctx); session != nil {
+		request.SetHeader("X-Auth-Token", session.token)
+	} else if username != "" {
+		request.SetBasicAuth(username, password)
+	}
+	if body != nil {
+		request.SetBody(body)
+	}
+
+	return request.Execute(method, link)
+}
+
+func (app *application) testIPMIConnection(ctx context.Context, hostname string, port int, username string, password string) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing IPMI connection to %s:%d", hostname, port))
 
+	result := newCheckResult("IPMI", hostname, port)
+
 	client, err := ipmi.NewClient(hostname, port, username, password)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to create client for IPMI connection to %s:%d - %s", hostname, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	client.Interface = "lanplus"
+	client.WithTimeout(app.timeouts(ctx).Read)
 
 	if err := client.Connect(ctx); err != nil {
 		slog.Error(fmt.Sprintf("Failed to open IPMI connection to %s:%d - %s", hostname, port, err.Error()))
-		return 1
+		result = result.fail(err)
+		// The session is opened with ADMINISTRATOR privilege, which the BMC refuses to lower privileged users
+		if strings.Contains(err.Error(), "SetSessionPrivilegeLevel") {
+			result.Error = fmt.Sprintf("user %s does not have ADMINISTRATOR privilege - %s", username, err.Error())
+			result.ErrorClass = "forbidden"
+		} else if result.ErrorClass == "error" && strings.Contains(err.Error(), "rakp") {
+			result.ErrorClass = "auth"
+		}
+		return result
 	}
+	defer client.Close(ctx)
 
 	response, err := client.GetSystemGUID(ctx)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed test for IPMI connection to %s:%d - %s", hostname, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	slog.Debug(fmt.Sprintf("Got IPMI response from %s:%d\n%s", hostname, port, response.Format()))
 
-	return 0
+	problems, warnings := inspectIPMI(ctx, client, &result)
+
+	for _, warning := range warnings {
+		slog.Warn(fmt.Sprintf("IPMI service on %s:%d - %s", hostname, port, warning))
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			slog.Error(fmt.Sprintf("IPMI service on %s:%d - %s", hostname, port, problem))
+		}
+		result = result.fail(errors.New(strings.Join(problems, "; ")))
+		result.ErrorClass = "capability"
+		return result
+	}
+
+	if len(warnings) > 0 {
+		result = result.warn(errors.New(strings.Join(warnings, "; ")))
+		result.ErrorClass = "capability"
+		return result
+	}
+
+	return result.pass()
 }
 
-func (app *application) testVNCConnection(ctx context.Context, hostname string, port int, password string) int {
+func (app *application) testVNCConnection(ctx context.Context, hostname string, port int, password string) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing VNC connection to %s:%d", hostname, port))
 
-	conn, err := net.Dial("tcp", net.JoinHostPort(hostname, strconv.Itoa(port)))
+	result := newCheckResult("VNC", hostname, port)
+	timeouts := app.timeouts(ctx)
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(hostname, strconv.Itoa(port)), timeouts.Connect)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to open VNC connection to %s:%d - %s", hostname, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer conn.Close()
 
+	err = conn.SetDeadline(time.Now().Add(timeouts.Read))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to set deadline for VNC connection to %s:%d - %s", hostname, port, err.Error()))
+		return result.fail(err)
+	}
+
 	client, err := vnc.Client(conn, &vnc.ClientConfig{
 		Auth: []vnc.ClientAuth{
 			&vnc.PasswordAuth{Password: password},
@@ -419,29 +686,29 @@ func (app *application) testVNCConnection(ctx context.Context, hostname string,
 	})
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to create client for VNC connection to %s:%d - %s", hostname, port, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer client.Close()
 
 	slog.Debug(fmt.Sprintf("Successfully connected to VNC server %s:%d", hostname, port))
 
-	return 0
+	return result.pass()
 }
 
-func (app *application) testWebSocketConnection(ctx context.Context, hostname string, port int, path string, secure bool) int {
+func (app *application) testWebSocketConnection(ctx context.Context, hostname string, port int, path string, secure bool) CheckResult {
 	slog.Debug(fmt.Sprintf("Testing WebSocket connection to %s:%d", hostname, port))
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	result := newCheckResult("WS", hostname, port)
+	if secure {
+		result = newCheckResult("WSS", hostname, port)
 	}
 
-	client := &http.Client{Transport: tr}
-	client.Timeout = TIMEOUT
+	timeouts := app.timeouts(ctx)
 	options := websocket.DialOptions{
-		HTTPClient: client,
+		HTTPClient: timeouts.httpClient(true),
 	}
 
-	timedCtx, cancel := context.WithTimeout(ctx, TIMEOUT)
+	timedCtx, cancel := context.WithTimeout(ctx, timeouts.total())
 	defer cancel()
 
 	var uri string
@@ -455,10 +722,10 @@ func (app *application) testWebSocketConnection(ctx context.Context, hostname st
 	if err != nil {
 		if strings.Contains(err.Error(), "expected handshake response status code 101 but got ") {
 			slog.Debug(fmt.Sprintf("WebSocket %s returned error '%s' - connection OK", uri, err.Error()))
-			return 0
+			return result.pass()
 		}
 		slog.Error(fmt.Sprintf("Failed to open WebSocket connection to %s - %s", uri, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	defer ws.Close(websocket.StatusNormalClosure, "")
 	slog.Debug(fmt.Sprintf("WebSocket %s connection established", uri))
@@ -484,7 +751,7 @@ func (app *application) testWebSocketConnection(ctx context.Context, hostname st
 	payloadJson, err := json.Marshal(payload)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to create test request: %s", err.Error()))
-		return 1
+		return result.fail(err)
 	}
 
 	message := map[string]string{
@@ -494,18 +761,18 @@ func (app *application) testWebSocketConnection(ctx context.Context, hostname st
 	err = wsjson.Write(timedCtx, ws, message)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to send WebSocket message to %s - %s", uri, err.Error()))
-		return 1
+		return result.fail(err)
 	}
 	slog.Debug(fmt.Sprintf("Sent WebSocket message to %s", uri))
 
 	messageType, responseMessage, err := ws.Read(timedCtx)
 	if err != nil {
 		slog.Debug(fmt.Sprintf("Failed to read WebSocket message from %s - %s - connection OK", uri, err.Error()))
-		return 0
+		return result.pass()
 	}
 	slog.Debug(fmt.Sprintf("Received WebSocket message from %s of type %v: %+v", uri, messageType, responseMessage))
 
 	slog.Debug(fmt.Sprintf("Successfully connected to WebSocket %s", uri))
 
-	return 0
+	return result.pass()
 }
@@ -10,14 +10,14 @@ import (
 	"time"
 )
 
-func (app *application) startHTTPServer(ctx context.Context, ip netip.Addr, port uint16) {
+func (app *application) startHTTPServer(ctx context.Context, ip netip.Addr, port uint16, handler http.Handler) {
 	defer app.wg.Done()
 
 	address := netip.AddrPortFrom(ip, port).String()
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
-		Handler:      http.HandlerFunc(app.httpRequestHandler),
+		Handler:      handler,
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
@@ -31,7 +31,7 @@ func (app *application) startHTTPServer(ctx context.Context, ip netip.Addr, port
 		slog.Info(fmt.Sprintf("Shutting down HTTP server on %s", address))
 
 		if err := srv.Shutdown(ctxShutdown); err != nil {
-			slog.Error("Error shutting down HTTP server on %s - %s", address, err.Error())
+			slog.Error(fmt.Sprintf("Error shutting down HTTP server on %s - %s", address, err.Error()))
 		}
 	}()
 
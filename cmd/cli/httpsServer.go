@@ -13,37 +13,20 @@ import (
 	"metalsoft.io/prerequisite-check/certs"
 )
 
-func (app *application) startHTTPSServer(ctx context.Context, ip netip.Addr, port uint16) {
+func (app *application) startHTTPSServer(ctx context.Context, ip netip.Addr, port uint16, handler http.Handler) {
 	defer app.wg.Done()
 
 	address := netip.AddrPortFrom(ip, port).String()
 
-	var err error
-
-	tlsConfig := &tls.Config{
-		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
-		MinVersion:       tls.VersionTLS13,
-		Certificates:     make([]tls.Certificate, 1),
-	}
-	certPEMBlock, err := certs.GetCert("cert.pem")
+	tlsConfig, err := serverTLSConfig()
 	if err != nil {
-		slog.Error("Error loading TLS certificate", err)
-		return
-	}
-	keyPEMBlock, err := certs.GetCert("key.pem")
-	if err != nil {
-		slog.Error("Error loading TLS key", err)
-		return
-	}
-	tlsConfig.Certificates[0], err = tls.X509KeyPair(certPEMBlock, keyPEMBlock)
-	if err != nil {
-		slog.Error("Error loading TLS certificate and key", err)
+		slog.Error(fmt.Sprintf("Error starting HTTPS server on %s - %s", address, err.Error()))
 		return
 	}
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
-		Handler:      http.HandlerFunc(app.httpsRequestHandler),
+		Handler:      handler,
 		TLSConfig:    tlsConfig,
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
@@ -73,6 +56,29 @@ func (app *application) startHTTPSServer(ctx context.Context, ip netip.Addr, por
 	slog.Info(fmt.Sprintf("HTTPS server on %s shut down", address))
 }
 
+// serverTLSConfig loads the certificate embedded in the tool for the HTTPS services.
+func serverTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		MinVersion:       tls.VersionTLS13,
+		Certificates:     make([]tls.Certificate, 1),
+	}
+	certPEMBlock, err := certs.GetCert("cert.pem")
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS certificate - %s", err.Error())
+	}
+	keyPEMBlock, err := certs.GetCert("key.pem")
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS key - %s", err.Error())
+	}
+	tlsConfig.Certificates[0], err = tls.X509KeyPair(certPEMBlock, keyPEMBlock)
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS certificate and key - %s", err.Error())
+	}
+
+	return tlsConfig, nil
+}
+
 func (app *application) httpsRequestHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Debug(fmt.Sprintf("HTTPS request received from %s: %s %s%s", r.RemoteAddr, r.Method, r.Host, r.URL.Path))
 
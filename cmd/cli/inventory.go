@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Prefixes of inventory cells that reference a secret instead of containing it
+const (
+	INVENTORY_ENV_PREFIX  = "env:"
+	INVENTORY_FILE_PREFIX = "file:"
+)
+
+// loadInventory reads a CSV file with a header row and returns the arguments for every device.
+// Empty cells take the value of the command argument with the same name.
+func loadInventory(fileName string, args map[string]string, columns argumentsList) ([]map[string]string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read header - %s", err.Error())
+	}
+	for index := range header {
+		header[index] = strings.ToLower(strings.TrimSpace(header[index]))
+		if !slices.ContainsFunc(columns, func(column argumentDetails) bool { return column.key == header[index] }) {
+			return nil, fmt.Errorf("unknown column %s", header[index])
+		}
+	}
+
+	rows := []map[string]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		row := maps.Clone(args)
+		delete(row, "inventory")
+		for index, value := range record {
+			value = strings.TrimSpace(value)
+			if value != "" {
+				row[header[index]] = value
+			}
+		}
+
+		for _, column := range columns {
+			if column.sensitive {
+				row[column.key], err = resolveInventorySecret(row[column.key])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s - %s", line, column.key, err.Error())
+				}
+			}
+			if column.required && row[column.key] == "" {
+				return nil, fmt.Errorf("line %d: missing %s", line, column.key)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no devices found")
+	}
+
+	return rows, nil
+}
+
+func resolveInventorySecret(value string) (string, error) {
+	var err error
+	if reference, found := strings.CutPrefix(value, INVENTORY_ENV_PREFIX); found {
+		value, err = readSecret(SECRET_ENV_SUFFIX, reference)
+	} else if reference, found := strings.CutPrefix(value, INVENTORY_FILE_PREFIX); found {
+		value, err = readSecret(SECRET_FILE_SUFFIX, reference)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	registerSecret(value)
+
+	return value, nil
+}
+
+// runInventory executes the profile for every device of the inventory, sharing the worker pool between devices.
+func (app *application) runInventory(ctx context.Context, profileName string, rows []map[string]string, deviceKey string) checkResults {
+	profile, err := loadEmbeddedProfile(profileName)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to load embedded profile %s - %s", profileName, err.Error()))
+		app.setExitCode(exitInternal)
+		return checkResults{}
+	}
+
+	checks := []checkFunc{}
+	for _, row := range rows {
+		device := row[deviceKey]
+		for _, check := range app.profileChecks(profile, row, nil) {
+			checks = append(checks, func(ctx context.Context) CheckResult {
+				result := check(ctx)
+				result.Device = device
+				return result
+			})
+		}
+	}
+
+	return app.runChecks(ctx, checks)
+}
+
+// logDeviceMatrix logs a table with the status of every check for every device.
+func logDeviceMatrix(title string, results checkResults) {
+	devices := []string{}
+	columns := []string{}
+	cells := make(map[string]map[string]CheckStatus)
+
+	for _, result := range results {
+		column := result.Protocol
+		if result.Port > 0 {
+			column += " " + strconv.Itoa(result.Port)
+		}
+
+		if !slices.Contains(devices, result.Device) {
+			devices = append(devices, result.Device)
+			cells[result.Device] = make(map[string]CheckStatus)
+		}
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+
+		// Keep the worst status when a device has several checks in the same column
+		if current, ok := cells[result.Device][column]; !ok || statusSeverity(result.Status) > statusSeverity(current) {
+			cells[result.Device][column] = result.Status
+		}
+	}
+
+	var table strings.Builder
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "%s\t%s\t\n", title, strings.Join(columns, "\t"))
+	for _, device := range devices {
+		row := []string{device}
+		for _, column := range columns {
+			status, ok := cells[device][column]
+			if !ok {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, strings.ToUpper(string(status)))
+		}
+		fmt.Fprintf(writer, "%s\t\n", strings.Join(row, "\t"))
+	}
+	writer.Flush()
+
+	slog.Info(fmt.Sprintf("%s matrix:\n%s", title, strings.TrimRight(table.String(), "\n")))
+}
+
+func statusSeverity(status CheckStatus) int {
+	switch status {
+	case StatusSkip:
+		return 1
+	case StatusWarn:
+		return 2
+	case StatusFail:
+		return 3
+	default:
+		return 0
+	}
+}
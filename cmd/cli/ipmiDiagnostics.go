@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	ipmi "github.com/bougou/go-ipmi"
+)
+
+// RMCP+ cipher suites using RAKP-HMAC-SHA1 or RAKP-HMAC-SHA256 authentication with AES-CBC-128 encryption
+var strongCipherSuites = []ipmi.CipherSuiteID{ipmi.CipherSuiteID3, ipmi.CipherSuiteID17}
+
+// inspectIPMI reads the privilege of the session, the cipher suites, the chassis status and the Serial-over-LAN
+// configuration, and sorts what MetalSoft cannot rely on into problems and warnings.
+func inspectIPMI(ctx context.Context, client *ipmi.Client, result *CheckResult) ([]string, []string) {
+	problems := []string{}
+	warnings := []string{}
+
+	channel := ipmi.ChannelNumberSelf
+
+	// Privilege - MetalSoft power control needs an ADMINISTRATOR session
+	session, err := client.GetSessionInfo(ctx, &ipmi.GetSessionInfoRequest{SessionIndex: 0})
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("could not read session info - %s", err.Error()))
+	} else {
+		channel = session.ChannelNumber
+		result.addFact("SessionPrivilege", session.OperatingPrivilegeLevel.String())
+
+		if access, err := client.GetUserAccess(ctx, channel, session.UserID); err == nil {
+			result.addFact("UserPrivilegeLimit", access.MaxPrivLevel.String())
+		}
+
+		if session.OperatingPrivilegeLevel != ipmi.PrivilegeLevelAdministrator {
+			problems = append(problems, fmt.Sprintf("session privilege is %s - ADMINISTRATOR is required", session.OperatingPrivilegeLevel))
+		}
+	}
+
+	// Cipher suites
+	suites, err := enabledCipherSuites(ctx, client, channel)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("could not read cipher suites - %s", err.Error()))
+	} else {
+		ids := []string{}
+		weak := []string{}
+		for _, suite := range suites {
+			ids = append(ids, strconv.Itoa(int(suite)))
+			if suite != ipmi.CipherSuiteID0 && !slices.Contains(strongCipherSuites, suite) {
+				weak = append(weak, strconv.Itoa(int(suite)))
+			}
+		}
+		result.addFact("CipherSuites", strings.Join(ids, ","))
+
+		if slices.Contains(suites, ipmi.CipherSuiteID0) {
+			warnings = append(warnings, "cipher suite 0 is enabled - it allows access without a password")
+		}
+		if len(weak) > 0 {
+			warnings = append(warnings, fmt.Sprintf("weak cipher suites %s are enabled", strings.Join(weak, ",")))
+		}
+	}
+
+	// Chassis power
+	chassis, err := client.GetChassisStatus(ctx)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("could not read chassis status - %s", err.Error()))
+	} else {
+		result.addFact("PowerState", formatPowerState(chassis.PowerIsOn))
+		if chassis.PowerFault || chassis.PowerControlFault {
+			warnings = append(warnings, "chassis reports a power fault")
+		}
+	}
+
+	// Serial-over-LAN
+	sol := &ipmi.SOLConfigParam_SOLEnable{}
+	if err := client.GetSOLConfigParamFor(ctx, channel, sol); err != nil {
+		warnings = append(warnings, fmt.Sprintf("could not read Serial-over-LAN configuration - %s", err.Error()))
+	} else {
+		result.addFact("SOLEnabled", strconv.FormatBool(sol.EnableSOLPayload))
+		if !sol.EnableSOLPayload {
+			warnings = append(warnings, "Serial-over-LAN is disabled")
+		}
+	}
+
+	return problems, warnings
+}
+
+// enabledCipherSuites returns the cipher suites enabled for any privilege level on the channel.
+// BMCs not reporting the privilege levels are assumed to enable all the supported cipher suites.
+func enabledCipherSuites(ctx context.Context, client *ipmi.Client, channel uint8) ([]ipmi.CipherSuiteID, error) {
+	ids := &ipmi.LanConfigParam_CipherSuitesID{}
+	levels := &ipmi.LanConfigParam_CipherSuitesPrivLevel{}
+	count := &ipmi.LanConfigParam_CipherSuitesSupport{}
+
+	if client.GetLanConfigParamFor(ctx, channel, count) == nil &&
+		client.GetLanConfigParamFor(ctx, channel, ids) == nil &&
+		client.GetLanConfigParamFor(ctx, channel, levels) == nil {
+		suites := []ipmi.CipherSuiteID{}
+		for index := 0; index < int(count.Count) && index < len(ids.IDs); index++ {
+			if levels.PrivLevels[index] != ipmi.PrivilegeLevelUnspecified {
+				suites = append(suites, ids.IDs[index])
+			}
+		}
+		return suites, nil
+	}
+
+	records, err := client.GetAllChannelCipherSuites(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	suites := []ipmi.CipherSuiteID{}
+	for _, record := range records {
+		suites = append(suites, record.CipherSuitID)
+	}
+
+	return suites, nil
+}
+
+func formatPowerState(on bool) string {
+	if on {
+		return "On"
+	}
+	return "Off"
+}
@@ -0,0 +1,677 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	ipmi "github.com/bougou/go-ipmi"
+)
+
+const (
+	RMCP_HEADER_SIZE       = 4
+	RMCP_CLASS_ASF         = 0x06
+	RMCP_CLASS_IPMI        = 0x07
+	ASF_IANA               = 4542
+	IPMI_SESSION_HEADER_20 = 12
+	IPMI_MOCK_CHANNEL      = 0x01
+	IPMI_MOCK_USER_ID      = 0x02
+)
+
+// ipmiCipherSuite lists the authentication, integrity and confidentiality algorithms of a cipher suite.
+type ipmiCipherSuite struct {
+	id           ipmi.CipherSuiteID
+	authAlg      ipmi.AuthAlg
+	integrityAlg ipmi.IntegrityAlg
+	cryptAlg     ipmi.CryptAlg
+}
+
+// The mock BMC enables only the cipher suites recommended by inspectIPMI
+var mockCipherSuites = []ipmiCipherSuite{
+	{ipmi.CipherSuiteID3, ipmi.AuthAlgRAKP_HMAC_SHA1, ipmi.IntegrityAlg_HMAC_SHA1_96, ipmi.CryptAlg_AES_CBC_128},
+	{ipmi.CipherSuiteID17, ipmi.AuthAlgRAKP_HMAC_SHA256, ipmi.IntegrityAlg_HMAC_SHA256_128, ipmi.CryptAlg_AES_CBC_128},
+}
+
+// ipmiSession is an RMCP+ session negotiated through the Open Session and RAKP messages.
+type ipmiSession struct {
+	suite       ipmiCipherSuite
+	consoleID   uint32
+	bmcID       uint32
+	consoleRand [16]byte
+	bmcRand     [16]byte
+	role        uint8
+	username    string
+	sik         []byte
+	k1          []byte
+	k2          []byte
+	active      bool
+	opened      time.Time
+	privilege   ipmi.PrivilegeLevel
+	sequence    uint32
+}
+
+// ipmiResponder answers the IPMI over LAN requests of one server socket. Packets are handled one at a time.
+type ipmiResponder struct {
+	bmc      *mockBMC
+	sessions map[uint32]*ipmiSession
+}
+
+func (app *application) startIPMIServer(ctx context.Context, ip netip.Addr, port uint16, bmc *mockBMC) {
+	defer app.wg.Done()
+
+	address := netip.AddrPortFrom(ip, port).String()
+
+	slog.Info(fmt.Sprintf("Starting IPMI server on %s", address))
+
+	ln, err := net.ListenPacket("udp", net.UDPAddrFromAddrPort(netip.AddrPortFrom(ip, port)).String())
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error starting IPMI server on %s - %s", address, err.Error()))
+		return
+	}
+	defer ln.Close()
+
+	go func() {
+		<-ctx.Done()
+
+		slog.Info(fmt.Sprintf("Shutting down IPMI server on %s", address))
+
+		if err := ln.Close(); err != nil {
+			slog.Error(fmt.Sprintf("Error shutting down IPMI server on %s - %s", address, err.Error()))
+		}
+	}()
+
+	responder := &ipmiResponder{
+		bmc:      bmc,
+		sessions: map[uint32]*ipmiSession{},
+	}
+
+	buffer := make([]byte, 1024)
+
+	for {
+		bytesRead, peer, err := ln.ReadFrom(buffer)
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				slog.Info(fmt.Sprintf("IPMI server on %s shut down", address))
+				return
+			}
+			slog.Error(fmt.Sprintf("Could not read IPMI packet on %s - %s", address, err.Error()))
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		reply, err := responder.handlePacket(slices.Clone(buffer[:bytesRead]))
+		if err != nil {
+			slog.Debug(fmt.Sprintf("Dropped IPMI packet from %s - %s", peer.String(), err.Error()))
+			continue
+		}
+		if reply == nil {
+			continue
+		}
+
+		if _, err := ln.WriteTo(reply, peer); err != nil {
+			slog.Error(fmt.Sprintf("Error writing IPMI packet on %s - %s", address, err.Error()))
+		}
+	}
+}
+
+// handlePacket returns the reply to an RMCP packet, or nil if the packet is not answered.
+func (responder *ipmiResponder) handlePacket(packet []byte) ([]byte, error) {
+	if len(packet) < RMCP_HEADER_SIZE+1 || packet[0] != ipmi.RmcpVersion {
+		return nil, errors.New("not an RMCP packet")
+	}
+
+	switch packet[3] {
+	case RMCP_CLASS_ASF:
+		return responder.handlePing(packet)
+	case RMCP_CLASS_IPMI:
+	default:
+		return nil, fmt.Errorf("unsupported RMCP message class %#02x", packet[3])
+	}
+
+	if ipmi.AuthType(packet[4]) != ipmi.AuthTypeRMCPPlus {
+		return responder.handleSession15(packet)
+	}
+
+	if len(packet) < RMCP_HEADER_SIZE+IPMI_SESSION_HEADER_20 {
+		return nil, errors.New("IPMI v2.0 session header too short")
+	}
+	header := packet[RMCP_HEADER_SIZE : RMCP_HEADER_SIZE+IPMI_SESSION_HEADER_20]
+	authenticated := header[1]&0x40 != 0
+	encrypted := header[1]&0x80 != 0
+	payloadType := ipmi.PayloadType(header[1] & 0x3f)
+	sessionID := binary.LittleEndian.Uint32(header[2:6])
+	payloadLength := int(binary.LittleEndian.Uint16(header[10:12]))
+
+	payloadStart := RMCP_HEADER_SIZE + IPMI_SESSION_HEADER_20
+	if len(packet) < payloadStart+payloadLength {
+		return nil, errors.New("IPMI v2.0 payload too short")
+	}
+	payload := packet[payloadStart : payloadStart+payloadLength]
+
+	switch payloadType {
+	case ipmi.PayloadTypeRmcpOpenSessionRequest:
+		return responder.openSession(payload)
+	case ipmi.PayloadTypeRAKPMessage1:
+		return responder.rakp1(payload)
+	case ipmi.PayloadTypeRAKPMessage3:
+		return responder.rakp3(payload)
+	case ipmi.PayloadTypeIPMI:
+	default:
+		return nil, fmt.Errorf("unsupported payload type %#02x", payloadType)
+	}
+
+	// Messages outside a session
+	if sessionID == 0 {
+		return packSession20(nil, ipmi.PayloadTypeIPMI, responder.handleMessage(nil, payload))
+	}
+
+	session, ok := responder.sessions[sessionID]
+	if !ok || !session.active {
+		return nil, fmt.Errorf("session %#08x is not active", sessionID)
+	}
+
+	if authenticated {
+		hashFunc, size := integrityHash(session.suite.integrityAlg)
+		if len(packet) < payloadStart+payloadLength+size {
+			return nil, errors.New("IPMI v2.0 session trailer too short")
+		}
+		authCode := packet[len(packet)-size:]
+		if !hmac.Equal(authCode, computeHMAC(hashFunc, session.k1, packet[RMCP_HEADER_SIZE:len(packet)-size])[:size]) {
+			return nil, fmt.Errorf("invalid integrity check value in session %#08x", sessionID)
+		}
+	}
+
+	if encrypted {
+		var err error
+		payload, err = decryptIPMIPayload(session, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return packSession20(session, ipmi.PayloadTypeIPMI, responder.handleMessage(session, payload))
+}
+
+// handlePing answers the RMCP presence ping used to discover BMCs.
+func (responder *ipmiResponder) handlePing(packet []byte) ([]byte, error) {
+	if len(packet) < RMCP_HEADER_SIZE+8 || packet[8] != uint8(ipmi.MessageTypePing) {
+		return nil, errors.New("not an ASF presence ping")
+	}
+
+	pong := []byte{ipmi.RmcpVersion, 0x00, 0xff, RMCP_CLASS_ASF}
+	pong = binary.BigEndian.AppendUint32(pong, ASF_IANA)
+	pong = append(pong, 0x40, packet[9], 0x00, 0x10)
+	pong = binary.BigEndian.AppendUint32(pong, ASF_IANA)
+	pong = append(pong, 0x00, 0x00, 0x00, 0x00, 0x81, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+
+	return pong, nil
+}
+
+// handleSession15 answers the messages sent outside a session with the IPMI v1.5 format,
+// as ipmitool does to read the authentication capabilities before opening an RMCP+ session.
+func (responder *ipmiResponder) handleSession15(packet []byte) ([]byte, error) {
+	if ipmi.AuthType(packet[4]) != ipmi.AuthTypeNone || len(packet) < RMCP_HEADER_SIZE+10 {
+		return nil, errors.New("IPMI v1.5 sessions are not supported")
+	}
+
+	payloadLength := int(packet[13])
+	if len(packet) < RMCP_HEADER_SIZE+10+payloadLength {
+		return nil, errors.New("IPMI v1.5 payload too short")
+	}
+
+	message := responder.handleMessage(nil, packet[RMCP_HEADER_SIZE+10:RMCP_HEADER_SIZE+10+payloadLength])
+
+	reply := []byte{ipmi.RmcpVersion, 0x00, 0xff, RMCP_CLASS_IPMI, uint8(ipmi.AuthTypeNone), 0, 0, 0, 0, 0, 0, 0, 0, uint8(len(message))}
+	return append(reply, message...), nil
+}
+
+func (responder *ipmiResponder) openSession(payload []byte) ([]byte, error) {
+	if len(payload) < 32 {
+		return nil, errors.New("open session request too short")
+	}
+
+	tag := payload[0]
+	consoleID := binary.LittleEndian.Uint32(payload[4:8])
+
+	index := slices.IndexFunc(mockCipherSuites, func(suite ipmiCipherSuite) bool {
+		return suite.authAlg == ipmi.AuthAlg(payload[12]) && suite.integrityAlg == ipmi.IntegrityAlg(payload[20]) && suite.cryptAlg == ipmi.CryptAlg(payload[28])
+	})
+	if index == -1 {
+		slog.Debug(fmt.Sprintf("IPMI open session rejected - no cipher suite with algorithms %d/%d/%d", payload[12], payload[20], payload[28]))
+		response := binary.LittleEndian.AppendUint32([]byte{tag, uint8(ipmi.RmcpStatusCodeNoCipherSuiteMatch), 0, 0}, consoleID)
+		return packSession20(nil, ipmi.PayloadTypeRmcpOpenSessionResponse, response)
+	}
+
+	// Drop the sessions abandoned during the RAKP exchange
+	for id, session := range responder.sessions {
+		if !session.active && time.Since(session.opened) > time.Minute {
+			delete(responder.sessions, id)
+		}
+	}
+
+	session := &ipmiSession{
+		suite:     mockCipherSuites[index],
+		consoleID: consoleID,
+		opened:    time.Now(),
+		privilege: ipmi.PrivilegeLevelUser,
+	}
+	for session.bmcID == 0 || responder.sessions[session.bmcID] != nil {
+		session.bmcID = binary.LittleEndian.Uint32(randomIPMIBytes(4))
+	}
+	responder.sessions[session.bmcID] = session
+
+	slog.Debug(fmt.Sprintf("IPMI session %#08x opened with cipher suite %d", session.bmcID, session.suite.id))
+
+	response := []byte{tag, uint8(ipmi.RmcpStatusCodeNoErrors), uint8(ipmi.PrivilegeLevelAdministrator), 0}
+	response = binary.LittleEndian.AppendUint32(response, session.consoleID)
+	response = binary.LittleEndian.AppendUint32(response, session.bmcID)
+	response = append(response, 0x00, 0, 0, 8, uint8(session.suite.authAlg), 0, 0, 0)
+	response = append(response, 0x01, 0, 0, 8, uint8(session.suite.integrityAlg), 0, 0, 0)
+	response = append(response, 0x02, 0, 0, 8, uint8(session.suite.cryptAlg), 0, 0, 0)
+
+	return packSession20(nil, ipmi.PayloadTypeRmcpOpenSessionResponse, response)
+}
+
+func (responder *ipmiResponder) rakp1(payload []byte) ([]byte, error) {
+	if len(payload) < 28 || len(payload) < 28+int(payload[27]) {
+		return nil, errors.New("RAKP message 1 too short")
+	}
+
+	tag := payload[0]
+	session, ok := responder.sessions[binary.LittleEndian.Uint32(payload[4:8])]
+	if !ok {
+		return packSession20(nil, ipmi.PayloadTypeRAKPMessage2, []byte{tag, uint8(ipmi.RmcpStatusCodeInvalidSessionID), 0, 0, 0, 0, 0, 0})
+	}
+
+	copy(session.consoleRand[:], payload[8:24])
+	copy(session.bmcRand[:], randomIPMIBytes(16))
+	session.role = payload[24]
+	session.username = string(payload[28 : 28+int(payload[27])])
+
+	status := ipmi.RmcpStatusCodeNoErrors
+	if session.username != responder.bmc.username {
+		status = ipmi.RmcpStatusCodeUnauthorizedName
+	} else if ipmi.PrivilegeLevel(session.role&0x0f) > ipmi.PrivilegeLevelAdministrator {
+		status = ipmi.RmcpStatusCodeUnauthorizedRoleOfPriLevel
+	}
+
+	response := binary.LittleEndian.AppendUint32([]byte{tag, uint8(status), 0, 0}, session.consoleID)
+	if status != ipmi.RmcpStatusCodeNoErrors {
+		slog.Debug(fmt.Sprintf("IPMI session %#08x rejected for user %s - %s", session.bmcID, session.username, status))
+		delete(responder.sessions, session.bmcID)
+		return packSession20(nil, ipmi.PayloadTypeRAKPMessage2, response)
+	}
+
+	input := binary.LittleEndian.AppendUint32(nil, session.consoleID)
+	input = binary.LittleEndian.AppendUint32(input, session.bmcID)
+	input = append(input, session.consoleRand[:]...)
+	input = append(input, session.bmcRand[:]...)
+	input = append(input, responder.bmc.guid[:]...)
+	input = append(input, session.role, uint8(len(session.username)))
+	input = append(input, session.username...)
+
+	response = append(response, session.bmcRand[:]...)
+	response = append(response, responder.bmc.guid[:]...)
+	response = append(response, computeHMAC(rakpHash(session.suite.authAlg), responder.passwordKey(), input)...)
+
+	return packSession20(nil, ipmi.PayloadTypeRAKPMessage2, response)
+}
+
+func (responder *ipmiResponder) rakp3(payload []byte) ([]byte, error) {
+	if len(payload) < 8 {
+		return nil, errors.New("RAKP message 3 too short")
+	}
+
+	tag := payload[0]
+	session, ok := responder.sessions[binary.LittleEndian.Uint32(payload[4:8])]
+	if !ok {
+		return packSession20(nil, ipmi.PayloadTypeRAKPMessage4, []byte{tag, uint8(ipmi.RmcpStatusCodeInvalidSessionID), 0, 0, 0, 0, 0, 0})
+	}
+
+	// The console rejected the RAKP message 2 - the password does not match
+	if ipmi.RmcpStatusCode(payload[1]) != ipmi.RmcpStatusCodeNoErrors {
+		slog.Debug(fmt.Sprintf("IPMI session %#08x aborted by the console - %s", session.bmcID, ipmi.RmcpStatusCode(payload[1])))
+		delete(responder.sessions, session.bmcID)
+		return nil, nil
+	}
+
+	hashFunc := rakpHash(session.suite.authAlg)
+	key := responder.passwordKey()
+
+	input := append(session.bmcRand[:0:0], session.bmcRand[:]...)
+	input = binary.LittleEndian.AppendUint32(input, session.consoleID)
+	input = append(input, session.role, uint8(len(session.username)))
+	input = append(input, session.username...)
+
+	if !hmac.Equal(payload[8:], computeHMAC(hashFunc, key, input)) {
+		slog.Debug(fmt.Sprintf("IPMI session %#08x rejected for user %s - invalid password", session.bmcID, session.username))
+		delete(responder.sessions, session.bmcID)
+		response := binary.LittleEndian.AppendUint32([]byte{tag, uint8(ipmi.RmcpStatusCodeInvalidIntegrityCheckValue), 0, 0}, session.consoleID)
+		return packSession20(nil, ipmi.PayloadTypeRAKPMessage4, response)
+	}
+
+	input = append(session.consoleRand[:0:0], session.consoleRand[:]...)
+	input = append(input, session.bmcRand[:]...)
+	input = append(input, session.role, uint8(len(session.username)))
+	input = append(input, session.username...)
+	session.sik = computeHMAC(hashFunc, key, input)
+	session.k1 = computeHMAC(hashFunc, session.sik, bytes.Repeat([]byte{0x01}, 20))
+	session.k2 = computeHMAC(hashFunc, session.sik, bytes.Repeat([]byte{0x02}, 20))
+
+	input = append(session.consoleRand[:0:0], session.consoleRand[:]...)
+	input = binary.LittleEndian.AppendUint32(input, session.bmcID)
+	input = append(input, responder.bmc.guid[:]...)
+	integrityFunc, size := integrityHash(session.suite.integrityAlg)
+
+	response := binary.LittleEndian.AppendUint32([]byte{tag, uint8(ipmi.RmcpStatusCodeNoErrors), 0, 0}, session.consoleID)
+	response = append(response, computeHMAC(integrityFunc, session.sik, input)[:size]...)
+
+	session.active = true
+	slog.Debug(fmt.Sprintf("IPMI session %#08x activated for user %s", session.bmcID, session.username))
+
+	return packSession20(nil, ipmi.PayloadTypeRAKPMessage4, response)
+}
+
+// passwordKey is the password padded to 20 bytes, the key of the RAKP authentication codes.
+func (responder *ipmiResponder) passwordKey() []byte {
+	key := make([]byte, 20)
+	copy(key, responder.bmc.password)
+	return key
+}
+
+// handleMessage executes an IPMI request message and returns the response message.
+func (responder *ipmiResponder) handleMessage(session *ipmiSession, message []byte) []byte {
+	if len(message) < 7 {
+		return nil
+	}
+
+	netFn := ipmi.NetFn(message[1] >> 2)
+	command := message[5]
+	data := message[6 : len(message)-1]
+
+	completionCode, responseData := responder.executeCommand(session, netFn, command, data)
+
+	response := []byte{message[3], uint8(netFn+1)<<2 | message[4]&0x03, 0, message[0], message[4]&0xfc | message[1]&0x03, command, uint8(completionCode)}
+	response = append(response, responseData...)
+	response[2] = ipmiChecksum(response[0:2])
+	response = append(response, ipmiChecksum(response[3:]))
+
+	return response
+}
+
+func (responder *ipmiResponder) executeCommand(session *ipmiSession, netFn ipmi.NetFn, command uint8, data []byte) (ipmi.CompletionCode, []byte) {
+	is := func(c ipmi.Command) bool {
+		return c.NetFn == netFn && c.ID == command
+	}
+
+	// Commands available before a session is established
+	switch {
+	case is(ipmi.CommandGetChannelAuthCapabilities):
+		// IPMI v2.0 extended data, non-null usernames, RMCP+ sessions
+		return ipmi.CompletionCodeNormal, []byte{IPMI_MOCK_CHANNEL, 0x80, 0x04, 0x02, 0x00, 0x00, 0x00, 0x00}
+
+	case is(ipmi.CommandGetChannelCipherSuites):
+		if len(data) < 3 {
+			return ipmi.CompletionCodeRequestDataLengthInvalid, nil
+		}
+		records := []byte{}
+		for _, suite := range mockCipherSuites {
+			records = append(records, ipmi.StandardCipherSuite, uint8(suite.id),
+				ipmi.CipherAlgTagBitAuthMask|uint8(suite.authAlg),
+				ipmi.CipherAlgTagBitIntegrityMask|uint8(suite.integrityAlg),
+				ipmi.CipherAlgTagBitEncryptionMask|uint8(suite.cryptAlg))
+		}
+		start := min(int(data[2]&ipmi.MaxCipherSuiteListIndex)*16, len(records))
+		return ipmi.CompletionCodeNormal, append([]byte{IPMI_MOCK_CHANNEL}, records[start:min(start+16, len(records))]...)
+
+	case is(ipmi.CommandGetSystemGUID):
+		return ipmi.CompletionCodeNormal, responder.bmc.guid[:]
+	}
+
+	if session == nil {
+		return ipmi.CompletionCodeCannotExecuteCommandSecurityRestrict, nil
+	}
+
+	switch {
+	case is(ipmi.CommandGetDeviceID):
+		// Firmware 6.10, IPMI 2.0, manufacturer Dell (674)
+		return ipmi.CompletionCodeNormal, []byte{0x20, 0x81, 0x06, 0x10, 0x02, 0xbf, 0xa2, 0x02, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}
+
+	case is(ipmi.CommandSetSessionPrivilegeLevel):
+		if len(data) < 1 {
+			return ipmi.CompletionCodeRequestDataLengthInvalid, nil
+		}
+		level := ipmi.PrivilegeLevel(data[0] & 0x0f)
+		if level > ipmi.PrivilegeLevelAdministrator || level > ipmi.PrivilegeLevel(session.role&0x0f) {
+			return 0x81, nil
+		}
+		if level != ipmi.PrivilegeLevelUnspecified {
+			session.privilege = level
+		}
+		return ipmi.CompletionCodeNormal, []byte{uint8(session.privilege)}
+
+	case is(ipmi.CommandGetSessionInfo):
+		// Channel 1 with the RMCP+ protocol
+		return ipmi.CompletionCodeNormal, []byte{0x01, 0x04, uint8(len(responder.sessions)), IPMI_MOCK_USER_ID, uint8(session.privilege), 0x10 | IPMI_MOCK_CHANNEL}
+
+	case is(ipmi.CommandGetUserAccess):
+		if len(data) < 2 {
+			return ipmi.CompletionCodeRequestDataLengthInvalid, nil
+		}
+		access := uint8(ipmi.PrivilegeLevel(0x0f))
+		if data[1]&0x3f == IPMI_MOCK_USER_ID {
+			// Link authentication and IPMI messaging enabled
+			access = 0x30 | uint8(ipmi.PrivilegeLevelAdministrator)
+		}
+		return ipmi.CompletionCodeNormal, []byte{0x10, 0x40 | 0x01, 0x01, access}
+
+	case is(ipmi.CommandCloseSession):
+		if len(data) < 4 {
+			return ipmi.CompletionCodeRequestDataLengthInvalid, nil
+		}
+		sessionID := binary.LittleEndian.Uint32(data[0:4])
+		if _, ok := responder.sessions[sessionID]; !ok {
+			return 0x87, nil
+		}
+		delete(responder.sessions, sessionID)
+		slog.Debug(fmt.Sprintf("IPMI session %#08x closed", sessionID))
+		return ipmi.CompletionCodeNormal, nil
+
+	case is(ipmi.CommandGetLanConfigParam):
+		if len(data) < 2 {
+			return ipmi.CompletionCodeRequestDataLengthInvalid, nil
+		}
+		switch ipmi.LanConfigParamSelector(data[1]) {
+		case ipmi.LanConfigParamSelector_CipherSuitesSupport:
+			return ipmi.CompletionCodeNormal, []byte{0x11, uint8(len(mockCipherSuites))}
+		case ipmi.LanConfigParamSelector_CipherSuitesID:
+			ids := []byte{0x11, 0x00}
+			for _, suite := range mockCipherSuites {
+				ids = append(ids, uint8(suite.id))
+			}
+			return ipmi.CompletionCodeNormal, ids
+		case ipmi.LanConfigParamSelector_CipherSuitesPrivLevel:
+			// Two cipher suites per byte, the maximum privilege level in each nibble
+			levels := make([]byte, 10)
+			levels[0] = 0x11
+			for index := range mockCipherSuites {
+				levels[2+index/2] |= uint8(ipmi.PrivilegeLevelAdministrator) << (4 * (index % 2))
+			}
+			return ipmi.CompletionCodeNormal, levels
+		default:
+			return 0x80, nil
+		}
+
+	case is(ipmi.CommandGetSOLConfigParam):
+		if len(data) < 2 {
+			return ipmi.CompletionCodeRequestDataLengthInvalid, nil
+		}
+		switch ipmi.SOLConfigParamSelector(data[1]) {
+		case ipmi.SOLConfigParamSelector_SetInProgress:
+			return ipmi.CompletionCodeNormal, []byte{0x11, 0x00}
+		case ipmi.SOLConfigParamSelector_SOLEnable:
+			return ipmi.CompletionCodeNormal, []byte{0x11, 0x01}
+		default:
+			return 0x80, nil
+		}
+
+	case is(ipmi.CommandGetChassisStatus):
+		// Power restore policy "always on", chassis identify supported
+		status := uint8(0x40)
+		if responder.bmc.power() {
+			status |= 0x01
+		}
+		return ipmi.CompletionCodeNormal, []byte{status, 0x00, 0x40}
+
+	case is(ipmi.CommandChassisControl):
+		if len(data) < 1 {
+			return ipmi.CompletionCodeRequestDataLengthInvalid, nil
+		}
+		if session.privilege < ipmi.PrivilegeLevelOperator {
+			return ipmi.CompletionCodeCannotExecuteCommandSecurityRestrict, nil
+		}
+		switch ipmi.ChassisControl(data[0] & 0x0f) {
+		case ipmi.ChassisControlPowerDown, ipmi.ChassisControlSoftShutdown:
+			responder.bmc.setPower(false)
+		case ipmi.ChassisControlPowerUp, ipmi.ChassisControlPowerCycle, ipmi.ChassisControlHardReset:
+			responder.bmc.setPower(true)
+		default:
+			return ipmi.CompletionCodeParameterOutOfRange, nil
+		}
+		return ipmi.CompletionCodeNormal, nil
+	}
+
+	slog.Debug(fmt.Sprintf("Unsupported IPMI command %#02x with network function %#02x", command, uint8(netFn)))
+
+	return ipmi.CompletionCodeInvalidCommand, nil
+}
+
+// packSession20 wraps the payload in an RMCP+ packet, encrypted and authenticated when sent in an active session.
+func packSession20(session *ipmiSession, payloadType ipmi.PayloadType, payload []byte) ([]byte, error) {
+	if payload == nil {
+		return nil, nil
+	}
+
+	packet := []byte{ipmi.RmcpVersion, 0x00, 0xff, RMCP_CLASS_IPMI, uint8(ipmi.AuthTypeRMCPPlus)}
+
+	if session == nil {
+		packet = append(packet, uint8(payloadType), 0, 0, 0, 0, 0, 0, 0, 0)
+		packet = binary.LittleEndian.AppendUint16(packet, uint16(len(payload)))
+		return append(packet, payload...), nil
+	}
+
+	encrypted, err := encryptIPMIPayload(session, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	session.sequence++
+	packet = append(packet, 0xc0|uint8(payloadType))
+	packet = binary.LittleEndian.AppendUint32(packet, session.consoleID)
+	packet = binary.LittleEndian.AppendUint32(packet, session.sequence)
+	packet = binary.LittleEndian.AppendUint16(packet, uint16(len(encrypted)))
+	packet = append(packet, encrypted...)
+
+	// Session trailer - integrity pad to a multiple of 4 bytes, pad length, next header and the authentication code
+	for (len(packet)-RMCP_HEADER_SIZE+2)%4 != 0 {
+		packet = append(packet, 0xff)
+	}
+	packet = append(packet, uint8((4-(len(encrypted)+IPMI_SESSION_HEADER_20+2)%4)%4), 0x07)
+
+	hashFunc, size := integrityHash(session.suite.integrityAlg)
+	packet = append(packet, computeHMAC(hashFunc, session.k1, packet[RMCP_HEADER_SIZE:])[:size]...)
+
+	return packet, nil
+}
+
+// encryptIPMIPayload encrypts with AES-CBC-128 - a random IV followed by the payload padded with 1, 2, 3... and the pad length.
+func encryptIPMIPayload(session *ipmiSession, payload []byte) ([]byte, error) {
+	block, err := aes.NewCipher(session.k2[:16])
+	if err != nil {
+		return nil, err
+	}
+
+	padded := slices.Clone(payload)
+	padLength := (aes.BlockSize - (len(payload)+1)%aes.BlockSize) % aes.BlockSize
+	for index := range padLength {
+		padded = append(padded, uint8(index+1))
+	}
+	padded = append(padded, uint8(padLength))
+
+	iv := randomIPMIBytes(aes.BlockSize)
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	return append(iv, encrypted...), nil
+}
+
+func decryptIPMIPayload(session *ipmiSession, payload []byte) ([]byte, error) {
+	if len(payload) < 2*aes.BlockSize || len(payload)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted payload length")
+	}
+
+	block, err := aes.NewCipher(session.k2[:16])
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make([]byte, len(payload)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, payload[:aes.BlockSize]).CryptBlocks(decrypted, payload[aes.BlockSize:])
+
+	padLength := int(decrypted[len(decrypted)-1])
+	if padLength >= len(decrypted) {
+		return nil, errors.New("invalid encrypted payload padding")
+	}
+
+	return decrypted[:len(decrypted)-padLength-1], nil
+}
+
+func rakpHash(authAlg ipmi.AuthAlg) func() hash.Hash {
+	if authAlg == ipmi.AuthAlgRAKP_HMAC_SHA256 {
+		return sha256.New
+	}
+	return sha1.New
+}
+
+// integrityHash returns the hash of the integrity algorithm and the length of the authentication code.
+func integrityHash(integrityAlg ipmi.IntegrityAlg) (func() hash.Hash, int) {
+	if integrityAlg == ipmi.IntegrityAlg_HMAC_SHA256_128 {
+		return sha256.New, 16
+	}
+	return sha1.New, 12
+}
+
+func computeHMAC(hashFunc func() hash.Hash, key []byte, data []byte) []byte {
+	mac := hmac.New(hashFunc, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func ipmiChecksum(data []byte) uint8 {
+	var sum uint8
+	for _, b := range data {
+		sum += b
+	}
+	return -sum
+}
+
+func randomIPMIBytes(length int) []byte {
+	data := make([]byte, length)
+	rand.Read(data)
+	return data
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Hostname   string          `xml:"hostname,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func junitSeconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+func (report checkReport) toJUnit() junitTestSuites {
+	suite := junitTestSuite{
+		Name:      report.Command,
+		Tests:     report.Summary.Total,
+		Failures:  report.Summary.Fail,
+		Skipped:   report.Summary.Skip,
+		Time:      junitSeconds(report.FinishedAt.Sub(report.StartedAt)),
+		Timestamp: report.StartedAt.Format("2006-01-02T15:04:05"),
+		Hostname:  report.Host.Hostname,
+		Properties: []junitProperty{
+			{Name: "version", Value: report.Tool.Version},
+			{Name: "githash", Value: report.Tool.GitHash},
+			{Name: "builddate", Value: report.Tool.BuildDate},
+		},
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(report.Arguments)) {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "argument." + key, Value: report.Arguments[key]})
+	}
+
+	for _, result := range report.Results {
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: report.Command + "." + strings.ToLower(result.Protocol),
+			Time:      junitSeconds(result.Duration),
+		}
+
+		switch result.Status {
+		case StatusFail:
+			testCase.Failure = &junitMessage{
+				Message: result.Error,
+				Type:    result.ErrorClass,
+				Text:    fmt.Sprintf("%s failed (%s) - %s", result.Name, result.ErrorClass, result.Error),
+			}
+		case StatusSkip:
+			testCase.Skipped = &junitMessage{Message: result.Error}
+		case StatusWarn:
+			testCase.SystemOut = fmt.Sprintf("WARNING: %s (%s) - %s", result.Name, result.ErrorClass, result.Error)
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	return junitTestSuites{
+		Name:     "ms-prerequisite-check",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+}
+
+func (report checkReport) writeJUnit(out io.Writer) error {
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report.toJUnit()); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(out, "\n")
+	return err
+}
@@ -14,6 +14,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 )
 
 var version string
@@ -21,15 +22,62 @@ var major, minor int
 var builddate string
 var githash string
 
+// Process exit codes
+const (
+	exitOK       = 0 // all checks passed
+	exitFailures = 1 // at least one check failed
+	exitWarnings = 2 // no check failed but some reported warnings
+	exitUsage    = 3 // invalid command line
+	exitInternal = 4 // the tool could not complete the checks
+)
+
 type application struct {
-	wg sync.WaitGroup
+	wg              sync.WaitGroup
+	mu              sync.Mutex
+	parallel        int
+	defaultTimeouts checkTimeouts
+	defaultRetries  retryPolicy
+	firmwarePolicy  *firmwarePolicy
+	results         checkResults
+	exitCode        int
+}
+
+// setExitCode records the exit code for the process, keeping the most severe one reported.
+func (app *application) setExitCode(code int) {
+	severity := func(code int) int {
+		switch code {
+		case exitWarnings:
+			return 1
+		case exitFailures:
+			return 2
+		case exitUsage:
+			return 3
+		case exitInternal:
+			return 4
+		default:
+			return 0
+		}
+	}
+
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	if severity(code) > severity(app.exitCode) {
+		app.exitCode = code
+	}
 }
 
 type runtimeConfiguration struct {
-	logLevel string
-	cmd      string
-	args     map[string]string
-	handler  func(context.Context, chan<- string, *application, map[string]string)
+	logLevel   string
+	parallel   int
+	timeouts   checkTimeouts
+	retries    retryPolicy
+	output     string
+	outputFile string
+	cmd        string
+	service    bool
+	args       map[string]string
+	handler    func(context.Context, chan<- string, *application, map[string]string)
 }
 
 func main() {
@@ -37,9 +85,20 @@ func main() {
 
 	major, minor, _, _ = parseVersionString(version)
 
-	slog.SetDefault(slog.New(NewLogHandler(os.Stdout, config.logLevel)))
+	// Keep the console log out of the way of a report written to stdout
+	logOut := os.Stdout
+	if config.output != "text" && config.outputFile == "" {
+		logOut = os.Stderr
+	}
+	slog.SetDefault(slog.New(NewRedactingHandler(NewLogHandler(logOut, config.logLevel))))
 
-	app := &application{}
+	startedAt := time.Now()
+
+	app := &application{
+		parallel:        config.parallel,
+		defaultTimeouts: config.timeouts,
+		defaultRetries:  config.retries,
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -68,6 +127,15 @@ func main() {
 
 	// Wait for all goroutines to finish
 	app.wg.Wait()
+
+	if config.output != "text" && !config.service {
+		if err := writeReport(config, newCheckReport(config, app.results, startedAt)); err != nil {
+			slog.Error(fmt.Sprintf("Failed to write %s report - %s", config.output, err.Error()))
+			app.setExitCode(exitInternal)
+		}
+	}
+
+	os.Exit(app.exitCode)
 }
 
 func processArguments() (conf runtimeConfiguration) {
@@ -81,10 +149,16 @@ func processArguments() (conf runtimeConfiguration) {
 			for _, argDetails := range cmdDetails.arguments {
 				arguments += fmt.Sprintf(" %s=<%s>", argDetails.key, argDetails.key)
 				argumentsHelp += fmt.Sprintf("        %s: ", argDetails.key)
-				if argDetails.required {
+				if argDetails.required && argDetails.requiredUnless != "" {
+					argumentsHelp += fmt.Sprintf("(required unless %s is set) ", argDetails.requiredUnless)
+				} else if argDetails.required {
 					argumentsHelp += "(required) "
 				}
 				argumentsHelp += argDetails.description
+				if argDetails.sensitive {
+					argumentsHelp += fmt.Sprintf(" Can be read with %s%s=<variable> or %s%s=<path>.",
+						argDetails.key, SECRET_ENV_SUFFIX, argDetails.key, SECRET_FILE_SUFFIX)
+				}
 				if argDetails.defaultValue != "" {
 					argumentsHelp += fmt.Sprintf(" [default: %s]", argDetails.defaultValue)
 				}
@@ -97,10 +171,21 @@ func processArguments() (conf runtimeConfiguration) {
 		flag.VisitAll(func(f *flag.Flag) {
 			fmt.Printf("  -%s\n      %v\n", f.Name, f.Usage)
 		})
+
+		fmt.Printf("\nExit codes:\n  %d - all checks passed\n  %d - at least one check failed\n  %d - only warnings were reported\n  %d - usage error\n  %d - internal error\n",
+			exitOK, exitFailures, exitWarnings, exitUsage, exitInternal)
 	}
 
 	flag.StringVar(&conf.logLevel, "log-level", "INFO", "Sets log level [default 'INFO']")
 
+	flag.IntVar(&conf.parallel, "parallel", 4, "Sets the maximum number of checks running in parallel [default 4]")
+	flag.DurationVar(&conf.timeouts.Connect, "connect-timeout", DEFAULT_CONNECT_TIMEOUT, "Sets the time allowed to connect to a target [default 10s]")
+	flag.DurationVar(&conf.timeouts.Read, "read-timeout", DEFAULT_READ_TIMEOUT, "Sets the time allowed to wait for a response from a target [default 10s]")
+	flag.IntVar(&conf.retries.Retries, "retries", 0, "Sets the number of times a failed check is repeated [default 0]")
+	flag.DurationVar(&conf.retries.Backoff, "backoff", DEFAULT_BACKOFF, "Sets the delay before the first retry, doubled for every following retry [default 1s]")
+	flag.StringVar(&conf.output, "output", "text", "Sets report output format - one of (text, json, junit) [default 'text']")
+	flag.StringVar(&conf.outputFile, "output-file", "", "Writes the report to the specified file instead of stdout")
+
 	var displayVersion bool
 	flag.BoolVar(&displayVersion, "version", false, "Display version and exit")
 	flag.BoolVar(&displayVersion, "v", false, "Display version and exit (short)")
@@ -108,11 +193,17 @@ func processArguments() (conf runtimeConfiguration) {
 	var debug bool
 	flag.BoolVar(&debug, "d", false, "Shortcut for --log-level=DEBUG")
 
-	flag.Parse()
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage)
+	}
 
 	if displayVersion {
 		fmt.Printf("Version:\t%s\nBuild date:\t%s\nGit hash:\t%s\n", version, builddate, githash)
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
 	if debug {
@@ -121,37 +212,74 @@ func processArguments() (conf runtimeConfiguration) {
 
 	if flag.NArg() < 1 {
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	if conf.timeouts.Connect <= 0 || conf.timeouts.Read <= 0 || conf.retries.Retries < 0 || conf.retries.Backoff < 0 {
+		fmt.Print("Invalid timeout or retry values\n\n")
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if conf.parallel < 1 {
+		fmt.Printf("Invalid parallel value: %d\n\n", conf.parallel)
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	conf.output = strings.ToLower(conf.output)
+	if !slices.Contains([]string{"text", "json", "junit"}, conf.output) {
+		fmt.Printf("Unsupported output format: %s\n\n", conf.output)
+		flag.Usage()
+		os.Exit(exitUsage)
 	}
 
 	conf.cmd = strings.ToLower(flag.Arg(0))
 	cmdIndex := slices.IndexFunc(commands, func(cmd commandDetails) bool { return cmd.key == conf.cmd })
 	if cmdIndex == -1 {
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	conf.args = make(map[string]string)
 	if len(conf.args) > len(commands[cmdIndex].arguments) {
 		fmt.Print("Too many arguments\n\n")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	for _, cmdArg := range flag.Args()[1:] {
 		if !strings.Contains(cmdArg, "=") {
 			flag.Usage()
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
-		argParts := strings.Split(cmdArg, "=")
+		// Split on the first '=' only, values such as passwords may contain it
+		argParts := strings.SplitN(cmdArg, "=", 2)
 		argName := strings.ToLower(argParts[0])
 		argValue := argParts[1]
 
+		if argDetails, suffix := secretSource(commands[cmdIndex].arguments, argName); argDetails != nil {
+			secret, err := readSecret(suffix, argValue)
+			if err != nil {
+				fmt.Printf("Failed to read %s from %s: %s\n\n", argDetails.key, argName, err.Error())
+				os.Exit(exitUsage)
+			}
+			registerSensitiveKey(argDetails.key)
+			registerSecret(secret)
+			conf.args[argDetails.key] = secret
+			continue
+		}
+
 		argIndex := slices.IndexFunc(commands[cmdIndex].arguments, func(arg argumentDetails) bool { return arg.key == argName })
-		if argIndex == -1 {
+		if argIndex == -1 && !commands[cmdIndex].anyArguments {
 			fmt.Printf("Unknown argument: %s\n\n", argName)
 			flag.Usage()
-			os.Exit(1)
+			os.Exit(exitUsage)
+		}
+
+		if (argIndex != -1 && commands[cmdIndex].arguments[argIndex].sensitive) || (argIndex == -1 && isSensitiveKey(argName) && !isSecretSource(argName)) {
+			registerSensitiveKey(argName)
+			registerSecret(argValue)
 		}
 
 		conf.args[argName] = argValue
@@ -159,10 +287,20 @@ func processArguments() (conf runtimeConfiguration) {
 
 	for _, argDetails := range commands[cmdIndex].arguments {
 		_, ok := conf.args[argDetails.key]
-		if !ok && argDetails.required {
+		required := argDetails.required && (argDetails.requiredUnless == "" || conf.args[argDetails.requiredUnless] == "")
+		if !ok && required && argDetails.sensitive {
+			// Prompt for secrets omitted from the command line to keep them out of the shell history
+			if secret, err := promptSecret(argDetails); err == nil && secret != "" {
+				registerSensitiveKey(argDetails.key)
+				registerSecret(secret)
+				conf.args[argDetails.key] = secret
+				continue
+			}
+		}
+		if !ok && required {
 			fmt.Printf("Missing required argument: %s\n\n", argDetails.key)
 			flag.Usage()
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
 		if !ok && argDetails.defaultValue != "" {
 			conf.args[argDetails.key] = argDetails.defaultValue
@@ -170,6 +308,7 @@ func processArguments() (conf runtimeConfiguration) {
 	}
 
 	conf.handler = commands[cmdIndex].handler
+	conf.service = commands[cmdIndex].service
 
 	return conf
 }
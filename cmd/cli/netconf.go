@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	NETCONF_NAMESPACE          = "urn:ietf:params:xml:ns:netconf:base:1.0"
+	NETCONF_BASE_10            = "urn:ietf:params:netconf:base:1.0"
+	NETCONF_BASE_11            = "urn:ietf:params:netconf:base:1.1"
+	NETCONF_END_OF_MESSAGE     = "]]>]]>"
+	NETCONF_MAX_MESSAGE_LENGTH = 1 << 20
+)
+
+// netconfConn frames NETCONF messages with the end-of-message marker of NETCONF 1.0,
+// or with the chunked framing of NETCONF 1.1 once both peers announced it in their hello.
+type netconfConn struct {
+	reader  *bufio.Reader
+	writer  io.Writer
+	chunked bool
+}
+
+func newNetconfConn(reader io.Reader, writer io.Writer) *netconfConn {
+	return &netconfConn{
+		reader: bufio.NewReader(reader),
+		writer: writer,
+	}
+}
+
+func (conn *netconfConn) readMessage() ([]byte, error) {
+	if conn.chunked {
+		return conn.readChunked()
+	}
+
+	data := []byte{}
+	for !bytes.HasSuffix(data, []byte(NETCONF_END_OF_MESSAGE)) {
+		b, err := conn.reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b)
+		if len(data) > NETCONF_MAX_MESSAGE_LENGTH {
+			return nil, errors.New("message too long")
+		}
+	}
+
+	return data[:len(data)-len(NETCONF_END_OF_MESSAGE)], nil
+}
+
+// readChunked reads the chunks "\n#<size>\n<data>" up to the end of chunks "\n##\n".
+func (conn *netconfConn) readChunked() ([]byte, error) {
+	data := []byte{}
+	for {
+		header, err := conn.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if header == "\n" {
+			continue
+		}
+
+		header = strings.TrimSuffix(header, "\n")
+		if header == "##" {
+			return data, nil
+		}
+		if !strings.HasPrefix(header, "#") {
+			return nil, fmt.Errorf("invalid chunk header %q", header)
+		}
+
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size <= 0 || len(data)+size > NETCONF_MAX_MESSAGE_LENGTH {
+			return nil, fmt.Errorf("invalid chunk size %q", header[1:])
+		}
+
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(conn.reader, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+	}
+}
+
+func (conn *netconfConn) writeMessage(message string) error {
+	if conn.chunked {
+		_, err := fmt.Fprintf(conn.writer, "\n#%d\n%s\n##\n", len(message), message)
+		return err
+	}
+
+	_, err := fmt.Fprintf(conn.writer, "%s%s", message, NETCONF_END_OF_MESSAGE)
+	return err
+}
+
+// netconfHello lists the capabilities announced by a peer - the session ID is sent by the server only.
+type netconfHello struct {
+	XMLName      xml.Name `xml:"hello"`
+	Capabilities []string `xml:"capabilities>capability"`
+	SessionID    string   `xml:"session-id"`
+}
+
+func (hello netconfHello) supports(capability string) bool {
+	return slices.ContainsFunc(hello.Capabilities, func(c string) bool {
+		return strings.TrimSpace(c) == capability
+	})
+}
+
+type netconfRPCError struct {
+	Tag      string `xml:"error-tag"`
+	Severity string `xml:"error-severity"`
+	Message  string `xml:"error-message"`
+}
+
+// junosSoftwareInformation is the reply to get-software-information. JunOS releases before 15.1
+// only report the version in the package comments, e.g. "JUNOS Base OS boot [12.3R12.4]".
+type junosSoftwareInformation struct {
+	HostName     string `xml:"host-name"`
+	ProductModel string `xml:"product-model"`
+	JunosVersion string `xml:"junos-version"`
+	Packages     []struct {
+		Comment string `xml:"comment"`
+	} `xml:"package-information"`
+}
+
+type netconfRPCReply struct {
+	XMLName   xml.Name                   `xml:"rpc-reply"`
+	MessageID string                     `xml:"message-id,attr"`
+	Errors    []netconfRPCError          `xml:"rpc-error"`
+	Software  []junosSoftwareInformation `xml:"software-information"`
+	// Switches with several routing engines or virtual chassis members
+	MultiRoutingEngine []junosSoftwareInformation `xml:"multi-routing-engine-results>multi-routing-engine-item>software-information"`
+}
+
+var junosPackageVersionRegexp = regexp.MustCompile(`\[([^\]]+)\]`)
+
+func (software junosSoftwareInformation) version() string {
+	if software.JunosVersion != "" {
+		return strings.TrimSpace(software.JunosVersion)
+	}
+
+	for _, pkg := range software.Packages {
+		if match := junosPackageVersionRegexp.FindStringSubmatch(pkg.Comment); match != nil {
+			return match[1]
+		}
+	}
+
+	return ""
+}
+
+// testNETCONFConnection opens the netconf SSH subsystem and exchanges the hello messages.
+// For JunOS it also executes get-software-information and reports the version.
+func (app *application) testNETCONFConnection(ctx context.Context, host string, port int, username string, password string, nos string) CheckResult {
+	slog.Debug(fmt.Sprintf("Testing NETCONF connection to %s:%d", host, port))
+
+	result := newCheckResult("NETCONF", host, port)
+	timeouts := app.timeouts(ctx)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	conn, err := net.DialTimeout("tcp", address, timeouts.Connect)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Unable to connect to NETCONF server %s - %s", address, err.Error()))
+		return result.fail(err)
+	}
+	defer conn.Close()
+
+	// The deadline covers the whole exchange, SSH does not time out reads of a channel
+	err = conn.SetDeadline(time.Now().Add(timeouts.total()))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to set deadline for NETCONF connection to %s - %s", address, err.Error()))
+		return result.fail(err)
+	}
+
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, app.sshClientConfig(ctx, username, password))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Unable to connect to NETCONF server %s - %s", address, err.Error()))
+		return result.fail(err)
+	}
+	client := ssh.NewClient(sshConn, channels, requests)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		slog.Error(fmt.Sprintf("Unable to create session with NETCONF server %s - %s", address, err.Error()))
+		return result.fail(err)
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return result.fail(err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return result.fail(err)
+	}
+
+	// SSH login succeeds on switches where NETCONF is not enabled, only the subsystem request fails
+	if err := session.RequestSubsystem("netconf"); err != nil {
+		slog.Error(fmt.Sprintf("NETCONF subsystem is not enabled on %s - %s", address, err.Error()))
+		result = result.fail(fmt.Errorf("NETCONF subsystem is not enabled - %s", err.Error()))
+		result.ErrorClass = "capability"
+		return result
+	}
+
+	netconf := newNetconfConn(stdout, stdin)
+
+	data, err := netconf.readMessage()
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to read NETCONF hello from %s - %s", address, err.Error()))
+		return result.fail(fmt.Errorf("could not read hello - %s", err.Error()))
+	}
+
+	hello := netconfHello{}
+	if err := xml.Unmarshal(data, &hello); err != nil {
+		slog.Error(fmt.Sprintf("Invalid NETCONF hello from %s - %s", address, err.Error()))
+		return result.fail(fmt.Errorf("invalid hello - %s", err.Error()))
+	}
+	if !hello.supports(NETCONF_BASE_10) && !hello.supports(NETCONF_BASE_11) {
+		slog.Error(fmt.Sprintf("NETCONF server %s does not announce a base capability", address))
+		result = result.fail(errors.New("hello does not announce the NETCONF base capability"))
+		result.ErrorClass = "capability"
+		return result
+	}
+
+	slog.Debug(fmt.Sprintf("NETCONF server %s announced session %s with capabilities\n%s", address, hello.SessionID, strings.Join(hello.Capabilities, "\n")))
+
+	err = netconf.writeMessage(fmt.Sprintf(`<hello xmlns="%s"><capabilities><capability>%s</capability><capability>%s</capability></capabilities></hello>`,
+		NETCONF_NAMESPACE, NETCONF_BASE_10, NETCONF_BASE_11))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to send NETCONF hello to %s - %s", address, err.Error()))
+		return result.fail(err)
+	}
+
+	netconf.chunked = hello.supports(NETCONF_BASE_11)
+	if netconf.chunked {
+		result.addFact("NETCONFBase", "1.1")
+	} else {
+		result.addFact("NETCONFBase", "1.0")
+	}
+	result.addFact("Capabilities", strconv.Itoa(len(hello.Capabilities)))
+
+	defer netconf.writeMessage(fmt.Sprintf(`<rpc xmlns="%s" message-id="2"><close-session/></rpc>`, NETCONF_NAMESPACE))
+
+	if !strings.EqualFold(nos, "junos") {
+		return result.pass()
+	}
+
+	err = netconf.writeMessage(fmt.Sprintf(`<rpc xmlns="%s" message-id="1"><get-software-information/></rpc>`, NETCONF_NAMESPACE))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to send NETCONF request to %s - %s", address, err.Error()))
+		return result.fail(err)
+	}
+
+	data, err = netconf.readMessage()
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to read NETCONF reply from %s - %s", address, err.Error()))
+		return result.fail(fmt.Errorf("could not read get-software-information reply - %s", err.Error()))
+	}
+
+	reply := netconfRPCReply{}
+	if err := xml.Unmarshal(data, &reply); err != nil {
+		slog.Error(fmt.Sprintf("Invalid NETCONF reply from %s - %s", address, err.Error()))
+		return result.fail(fmt.Errorf("invalid get-software-information reply - %s", err.Error()))
+	}
+
+	for _, rpcError := range reply.Errors {
+		if rpcError.Severity != "warning" {
+			slog.Warn(fmt.Sprintf("NETCONF server %s rejected get-software-information - %s %s", address, rpcError.Tag, rpcError.Message))
+			result = result.warn(fmt.Errorf("get-software-information failed - %s %s", rpcError.Tag, strings.TrimSpace(rpcError.Message)))
+			result.ErrorClass = "capability"
+			return result
+		}
+	}
+
+	software := append(reply.Software, reply.MultiRoutingEngine...)
+	if len(software) == 0 || software[0].version() == "" {
+		slog.Warn(fmt.Sprintf("NETCONF server %s did not report the JunOS version", address))
+		result = result.warn(errors.New("get-software-information did not report the JunOS version"))
+		result.ErrorClass = "capability"
+		return result
+	}
+
+	result.addFact("Hostname", strings.TrimSpace(software[0].HostName))
+	result.addFact("Model", strings.TrimSpace(software[0].ProductModel))
+	result.addFact("JunOSVersion", software[0].version())
+
+	slog.Debug(fmt.Sprintf("NETCONF server %s runs JunOS %s", address, software[0].version()))
+
+	return result.pass()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// netconfMessage is a hello or rpc message - the operation is the first element of the rpc.
+type netconfMessage struct {
+	XMLName      xml.Name
+	MessageID    string   `xml:"message-id,attr"`
+	Capabilities []string `xml:"capabilities>capability"`
+	Operation    struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// netconfSession is the server side of a NETCONF session over an SSH channel.
+type netconfSession struct {
+	conn      *netconfConn
+	sessionID int
+	hostname  string
+}
+
+// netconfSubsystem returns the handler of the "netconf" SSH subsystem of a mock switch.
+func netconfSubsystem(hostname string) func(channel ssh.Channel) {
+	var sessionID atomic.Int32
+
+	return func(channel ssh.Channel) {
+		session := &netconfSession{
+			conn:      newNetconfConn(channel, channel),
+			sessionID: int(sessionID.Add(1)),
+			hostname:  hostname,
+		}
+
+		if err := session.run(); err != nil && !errors.Is(err, io.EOF) {
+			slog.Debug(fmt.Sprintf("NETCONF session %d failed - %s", session.sessionID, err.Error()))
+			return
+		}
+
+		slog.Debug(fmt.Sprintf("NETCONF session %d closed", session.sessionID))
+	}
+}
+
+func (session *netconfSession) run() error {
+	hello := fmt.Sprintf(`<hello xmlns="%s"><capabilities><capability>%s</capability><capability>%s</capability></capabilities><session-id>%d</session-id></hello>`,
+		NETCONF_NAMESPACE, NETCONF_BASE_10, NETCONF_BASE_11, session.sessionID)
+	if err := session.conn.writeMessage(hello); err != nil {
+		return err
+	}
+
+	message, err := session.read()
+	if err != nil {
+		return err
+	}
+	if message.XMLName.Local != "hello" {
+		return fmt.Errorf("expected hello, received %s", message.XMLName.Local)
+	}
+
+	for _, capability := range message.Capabilities {
+		if strings.TrimSpace(capability) == NETCONF_BASE_11 {
+			session.conn.chunked = true
+		}
+	}
+
+	slog.Debug(fmt.Sprintf("NETCONF session %d established - capabilities %s", session.sessionID, strings.Join(message.Capabilities, ", ")))
+
+	for {
+		message, err := session.read()
+		if err != nil {
+			return err
+		}
+		if message.XMLName.Local != "rpc" {
+			return fmt.Errorf("expected rpc, received %s", message.XMLName.Local)
+		}
+
+		operation := message.Operation.XMLName.Local
+		slog.Debug(fmt.Sprintf("NETCONF session %d received rpc %s", session.sessionID, operation))
+
+		reply, closing := session.execute(operation)
+		err = session.conn.writeMessage(fmt.Sprintf(`<rpc-reply xmlns="%s" message-id="%s">%s</rpc-reply>`, NETCONF_NAMESPACE, xmlEscape(message.MessageID), reply))
+		if err != nil || closing {
+			return err
+		}
+	}
+}
+
+// execute returns the content of the rpc reply and whether the session ends.
+func (session *netconfSession) execute(operation string) (string, bool) {
+	switch operation {
+	case "get", "get-config":
+		return fmt.Sprintf(`<data><system xmlns="urn:ietf:params:xml:ns:yang:ietf-system"><hostname>%s</hostname></system></data>`, xmlEscape(session.hostname)), false
+	case "get-software-information":
+		return fmt.Sprintf(`<software-information><host-name>%s</host-name><product-model>%s</product-model><junos-version>%s</junos-version></software-information>`,
+			xmlEscape(session.hostname), MOCK_SWITCH_MODEL, MOCK_SWITCH_VERSION), false
+	case "close-session":
+		return "<ok/>", true
+	default:
+		return fmt.Sprintf(`<rpc-error><error-type>protocol</error-type><error-tag>operation-not-supported</error-tag><error-severity>error</error-severity><error-message>%s is not supported by the mock server</error-message></rpc-error>`, xmlEscape(operation)), false
+	}
+}
+
+func (session *netconfSession) read() (*netconfMessage, error) {
+	data, err := session.conn.readMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	message := &netconfMessage{}
+	if err := xml.Unmarshal(data, message); err != nil {
+		return nil, fmt.Errorf("invalid message - %s", err.Error())
+	}
+
+	return message, nil
+}
+
+func xmlEscape(text string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
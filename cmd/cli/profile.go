@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed profiles/*.yaml
+var profilesFS embed.FS
+
+// checkProfile describes a list of checks to be executed by the probe functions.
+type checkProfile struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Arguments   []profileArgument `yaml:"arguments"`
+	Checks      []checkDefinition `yaml:"checks"`
+}
+
+type profileArgument struct {
+	Key          string `yaml:"key"`
+	Description  string `yaml:"description"`
+	Required     bool   `yaml:"required"`
+	DefaultValue string `yaml:"default"`
+	Sensitive    bool   `yaml:"sensitive"`
+}
+
+type checkDefinition struct {
+	ID       string `yaml:"id"`
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	URL      string `yaml:"url"`
+	Path     string `yaml:"path"`
+	Kind     string `yaml:"kind"`
+	Secure   bool   `yaml:"secure"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Version  string `yaml:"version"`
+	// Overrides of the global timeouts and retry policy
+	ConnectTimeout time.Duration     `yaml:"connect-timeout"`
+	ReadTimeout    time.Duration     `yaml:"read-timeout"`
+	Retries        *int              `yaml:"retries"`
+	Backoff        time.Duration     `yaml:"backoff"`
+	When           map[string]string `yaml:"when"`
+	Expect         checkExpectation  `yaml:"expect"`
+	Tags           []string          `yaml:"tags"`
+}
+
+type checkExpectation struct {
+	// Accepted HTTP status codes for http, https and link checks
+	Status []int `yaml:"status"`
+	// Require a reply to the probe for tcp, tls and udp checks
+	Reply bool `yaml:"reply"`
+}
+
+var templateRegexp = regexp.MustCompile(`\$\{([a-z0-9-]+)\}`)
+
+var errMissingArgument = errors.New("missing argument")
+
+// loadProfile reads a profile file in YAML or JSON format.
+func loadProfile(fileName string) (*checkProfile, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProfile(data)
+}
+
+// loadEmbeddedProfile reads one of the default profiles embedded in the tool.
+func loadEmbeddedProfile(name string) (*checkProfile, error) {
+	data, err := profilesFS.ReadFile("profiles/" + name + ".yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProfile(data)
+}
+
+func parseProfile(data []byte) (*checkProfile, error) {
+	// JSON is a subset of YAML, so a single decoder handles both formats
+	profile := &checkProfile{}
+	if err := yaml.Unmarshal(data, profile); err != nil {
+		return nil, fmt.Errorf("could not parse profile - %s", err.Error())
+	}
+
+	for index, check := range profile.Checks {
+		if _, ok := checkDefaultPorts[strings.ToLower(check.Type)]; !ok {
+			checkTypes := slices.Sorted(maps.Keys(checkDefaultPorts))
+			return nil, fmt.Errorf("check %d has unknown type '%s' - expected one of (%s)", index+1, check.Type, strings.Join(checkTypes, ", "))
+		}
+	}
+
+	return profile, nil
+}
+
+// checkDefaultPorts lists the supported check types with the port used when the check does not set one.
+var checkDefaultPorts = map[string]int{
+	"http":      80,
+	"https":     443,
+	"link":      0,
+	"tcp":       0,
+	"tls":       0,
+	"udp":       0,
+	"icmp":      0,
+	"ssh":       22,
+	"netconf":   830,
+	"websocket": 443,
+	"redfish":   443,
+	"ipmi":      623,
+	"vnc":       5901,
+	// Walks the Redfish service and checks the capabilities needed to manage the server
+	"redfish-inventory": 443,
+	// Mounts url as virtual CD/DVD, kind selects the vendor paths
+	"virtual-media": 443,
+	// Reads the OS version and the user roles through the management API of the NOS selected by kind
+	"switch-api": 443,
+	// Reads the file at path, by default the test file served by the site-service command
+	"tftp": 69,
+}
+
+// checkProtocols lists the check types whose probes report a protocol other than the upper-case type.
+var checkProtocols = map[string]string{
+	"switch-api":        "SwitchAPI",
+	"websocket":         "WS",
+	"redfish":           "Redfish",
+	"redfish-inventory": "RedfishInventory",
+	"virtual-media":     "VirtualMedia",
+}
+
+// expand replaces ${argument} references with argument values.
+func expand(value string, args map[string]string) (string, error) {
+	var err error
+	expanded := templateRegexp.ReplaceAllStringFunc(value, func(match string) string {
+		key := templateRegexp.FindStringSubmatch(match)[1]
+		if args[key] == "" {
+			err = fmt.Errorf("%w %s", errMissingArgument, key)
+		}
+		return args[key]
+	})
+
+	return expanded, err
+}
+
+// expand replaces the argument references in every field, so that a check skipped for a missing argument still reports its target.
+func (check checkDefinition) expand(args map[string]string) (checkDefinition, error) {
+	var missing error
+	for _, field := range []*string{&check.Host, &check.Port, &check.URL, &check.Path, &check.Kind, &check.Username, &check.Password, &check.Name} {
+		var err error
+		if *field, err = expand(*field, args); err != nil && missing == nil {
+			missing = err
+		}
+	}
+
+	return check, missing
+}
+
+// applies reports if the version and argument conditions of the check are satisfied.
+func (check checkDefinition) applies(args map[string]string) bool {
+	for key, value := range check.When {
+		if !strings.EqualFold(args[key], value) {
+			return false
+		}
+	}
+
+	if check.Version == "" {
+		return true
+	}
+
+	constraint := strings.TrimLeft(check.Version, "<>=")
+	operator := strings.TrimSpace(strings.TrimSuffix(check.Version, constraint))
+	constraintMajor, constraintMinor, _, err := parseVersionString(constraint)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Ignoring invalid version condition '%s'", check.Version))
+		return true
+	}
+
+	comparison := major - constraintMajor
+	if comparison == 0 {
+		comparison = minor - constraintMinor
+	}
+
+	switch operator {
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	case ">=":
+		return comparison >= 0
+	default:
+		return comparison == 0
+	}
+}
+
+func (check checkDefinition) port(defaultPort int) (int, error) {
+	if check.Port == "" {
+		return defaultPort, nil
+	}
+
+	port, err := strconv.Atoi(check.Port)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port '%s'", check.Port)
+	}
+
+	return port, nil
+}
+
+func (check checkDefinition) hasTag(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+
+	for _, tag := range tags {
+		if slices.Contains(check.Tags, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runProfile executes the checks of the profile that apply to the arguments.
+func (app *application) runProfile(ctx context.Context, profile *checkProfile, args map[string]string, tags []string) checkResults {
+	return app.runChecks(ctx, app.profileChecks(profile, args, tags))
+}
+
+// credentialGate serializes the checks that log in to a device with the same credentials and stops them after a
+// rejected login, as parallel failed logins to a BMC or a switch lock the account.
+type credentialGate struct {
+	mu sync.Mutex
+	// Name of the check whose login was rejected
+	failure string
+}
+
+// profileChecks prepares the checks of the profile that apply to the arguments.
+func (app *application) profileChecks(profile *checkProfile, args map[string]string, tags []string) []checkFunc {
+	checks := []checkFunc{}
+	gates := map[string]*credentialGate{}
+
+	for _, definition := range profile.Checks {
+		if !definition.hasTag(tags) || !definition.applies(args) {
+			continue
+		}
+
+		check, err := definition.expand(args)
+		if errors.Is(err, errMissingArgument) {
+			checks = append(checks, func(ctx context.Context) CheckResult {
+				return check.skipped(err.Error())
+			})
+			continue
+		}
+
+		if check.Password == "" {
+			checks = append(checks, func(ctx context.Context) CheckResult {
+				return app.runCheck(ctx, check)
+			})
+			continue
+		}
+
+		key := check.Host + "\x00" + check.Username + "\x00" + check.Password
+		gate, ok := gates[key]
+		if !ok {
+			gate = &credentialGate{}
+			gates[key] = gate
+		}
+
+		checks = append(checks, func(ctx context.Context) CheckResult {
+			gate.mu.Lock()
+			defer gate.mu.Unlock()
+
+			if gate.failure != "" {
+				return check.skipped(fmt.Sprintf("not performed after the rejected login of check %s", gate.failure))
+			}
+
+			result := app.runCheck(ctx, check)
+			if result.ErrorClass == "auth" || result.ErrorClass == "account-locked" {
+				gate.failure = result.Name
+			}
+
+			return result
+		})
+	}
+
+	return checks
+}
+
+// runCheck executes a single check definition with the matching probe function.
+func (app *application) runCheck(ctx context.Context, check checkDefinition) CheckResult {
+	checkType := strings.ToLower(check.Type)
+
+	port, err := check.port(checkDefaultPorts[checkType])
+	if err != nil {
+		return check.invalid(err)
+	}
+
+	timeouts := app.timeouts(ctx)
+	if check.ConnectTimeout > 0 {
+		timeouts.Connect = check.ConnectTimeout
+	}
+	if check.ReadTimeout > 0 {
+		timeouts.Read = check.ReadTimeout
+	}
+
+	policy := app.defaultRetries
+	if check.Retries != nil {
+		policy.Retries = *check.Retries
+	}
+	if check.Backoff > 0 {
+		policy.Backoff = check.Backoff
+	}
+
+	result := app.retry(withCheckTimeouts(ctx, timeouts), policy, func(ctx context.Context) CheckResult {
+		return app.probe(ctx, check, checkType, port)
+	})
+
+	return check.evaluate(result)
+}
+
+// probe executes the probe function matching the check type.
+func (app *application) probe(ctx context.Context, check checkDefinition, checkType string, port int) CheckResult {
+	var result CheckResult
+
+	switch checkType {
+	case "http":
+		result = app.testHTTPConnection(ctx, check.Host, port)
+	case "https":
+		result = app.testHTTPSConnection(ctx, check.Host, port)
+	case "link":
+		result = app.testLink(ctx, check.URL)
+	case "tcp":
+		result = app.testTCPConnection(ctx, check.Host, port)
+	case "tls":
+		result = app.testEncryptedTCPConnection(ctx, check.Host, port)
+	case "udp":
+		result = app.testUDPConnection(ctx, check.Host, port, check.Kind)
+	case "icmp":
+		result = app.testICMPConnection(ctx, check.Host)
+	case "ssh":
+		result = app.testSSHConnection(ctx, check.Host, port, check.Username, check.Password)
+	case "netconf":
+		result = app.testNETCONFConnection(ctx, check.Host, port, check.Username, check.Password, check.Kind)
+	case "switch-api":
+		result = app.testSwitchAPI(ctx, check.Host, port, check.Username, check.Password, check.Kind)
+	case "tftp":
+		filename := check.Path
+		if filename == "" {
+			filename = TFTP_TEST_FILE
+		}
+		result = app.testTFTPTransfer(ctx, check.Host, port, filename)
+	case "websocket":
+		result = app.testWebSocketConnection(ctx, check.Host, port, check.Path, check.Secure)
+	case "redfish":
+		path := check.Path
+		if path == "" {
+			path = "/redfish/v1"
+		}
+		var data interface{}
+		data, result = app.testRedfishAPI(ctx, check.Host, port, check.Username, check.Password, path)
+		if data != nil {
+			result.addFact("RedfishVersion", safeConvert(data, "RedfishVersion"))
+			result.addFact("Vendor", safeConvert(data, "Vendor"))
+		}
+	case "redfish-inventory":
+		_, result = app.testRedfishInventory(ctx, check.Host, port, check.Username, check.Password)
+	case "ipmi":
+		result = app.testIPMIConnection(ctx, check.Host, port, check.Username, check.Password)
+	case "vnc":
+		result = app.testVNCConnection(ctx, check.Host, port, check.Password)
+	case "virtual-media":
+		result = app.testRedfishVirtualMedia(ctx, check.Host, port, check.Username, check.Password, check.Kind, check.URL)
+	default:
+		return check.invalid(fmt.Errorf("unknown check type '%s'", check.Type))
+	}
+
+	return result
+}
+
+// evaluate applies the check identity and expectations to the probe result.
+func (check checkDefinition) evaluate(result CheckResult) CheckResult {
+	if check.ID != "" {
+		result.ID = check.ID
+	}
+	if check.Name != "" {
+		result.Name = check.Name
+	}
+	result.Tags = check.Tags
+
+	if len(check.Expect.Status) > 0 && result.Status == StatusPass {
+		statusCode := 0
+		if fields := strings.Fields(result.Facts["status"]); len(fields) > 0 {
+			statusCode, _ = strconv.Atoi(fields[0])
+		}
+		if !slices.Contains(check.Expect.Status, statusCode) {
+			slog.Error(fmt.Sprintf("Failed check %s - unexpected status %s", result.Name, result.Facts["status"]))
+			result.Status = StatusFail
+			result.ErrorClass = "expectation"
+			result.Error = fmt.Sprintf("unexpected status %s - expected one of %v", result.Facts["status"], check.Expect.Status)
+		}
+	}
+
+	if check.Expect.Reply && result.Status == StatusPass && result.Facts["response"] == "none" {
+		slog.Error(fmt.Sprintf("Failed check %s - no reply from %s", result.Name, result.Target))
+		result.Status = StatusFail
+		result.ErrorClass = "expectation"
+		result.Error = "no reply received"
+	}
+
+	return result
+}
+
+// skipped reports a check that was not performed.
+func (check checkDefinition) skipped(reason string) CheckResult {
+	target := check.Host
+	if target == "" {
+		target = check.URL
+	}
+
+	slog.Info(fmt.Sprintf("Skipping %s check of %s - %s", check.Type, target, reason))
+
+	checkType := strings.ToLower(check.Type)
+	protocol, ok := checkProtocols[checkType]
+	if !ok {
+		protocol = strings.ToUpper(checkType)
+	}
+	port, _ := check.port(checkDefaultPorts[checkType])
+
+	result := newCheckResult(protocol, target, port)
+
+	return check.evaluate(result.skip(reason))
+}
+
+func (check checkDefinition) invalid(err error) CheckResult {
+	slog.Error(fmt.Sprintf("Invalid %s check definition - %s", check.Type, err.Error()))
+
+	result := newCheckResult(strings.ToUpper(check.Type), check.Host+check.URL, 0)
+	result.Tags = check.Tags
+	result = result.fail(err)
+	result.ErrorClass = "invalid-check"
+
+	return result
+}
+
+// runProfileCommand executes an arbitrary profile file.
+func runProfileCommand(ctx context.Context, endCh chan<- string, app *application, args map[string]string) {
+	profile, err := loadProfile(args["file"])
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to load profile %s - %s", args["file"], err.Error()))
+		app.setExitCode(exitUsage)
+		endCh <- "Profile check failed"
+		return
+	}
+
+	if err := resolveProfileSecrets(profile, args); err != nil {
+		slog.Error(fmt.Sprintf("Failed to read the arguments of profile %s - %s", profile.Name, err.Error()))
+		app.setExitCode(exitUsage)
+		endCh <- "Profile check failed"
+		return
+	}
+
+	// The sensitive arguments of the profile are registered before the arguments are logged
+	for _, argument := range profile.Arguments {
+		if args[argument.Key] == "" {
+			args[argument.Key] = argument.DefaultValue
+		}
+		if argument.Sensitive {
+			registerSensitiveKey(argument.Key)
+			registerSecret(args[argument.Key])
+		}
+	}
+
+	slog.Info("Starting profile check", "arguments", args)
+
+	for _, argument := range profile.Arguments {
+		if args[argument.Key] == "" && argument.Required {
+			slog.Error(fmt.Sprintf("Missing required argument for profile %s: %s", profile.Name, argument.Key))
+			app.setExitCode(exitUsage)
+			endCh <- "Profile check failed"
+			return
+		}
+	}
+
+	policy, err := loadFirmwarePolicy(args["firmware-policy"])
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to load firmware policy %s - %s", args["firmware-policy"], err.Error()))
+		app.setExitCode(exitUsage)
+		endCh <- "Profile check failed"
+		return
+	}
+	app.firmwarePolicy = policy
+
+	var tags []string
+	for _, tag := range strings.Split(args["tags"], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	title := "Profile check"
+	if profile.Name != "" {
+		title = fmt.Sprintf("Profile %s check", profile.Name)
+	}
+
+	app.summarize(title, app.runProfile(ctx, profile, args, tags))
+
+	endCh <- title + " completed"
+}
+
+// runEmbeddedProfile executes one of the default profiles that back the built-in check commands.
+func (app *application) runEmbeddedProfile(ctx context.Context, name string, args map[string]string) checkResults {
+	profile, err := loadEmbeddedProfile(name)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to load embedded profile %s - %s", name, err.Error()))
+		app.setExitCode(exitInternal)
+		return checkResults{}
+	}
+
+	return app.runProfile(ctx, profile, args, nil)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const REDACTED = "********"
+
+// Secrets shorter than this are not redacted from free text, as replacing them would mangle unrelated output.
+const MIN_EMBEDDED_SECRET_LENGTH = 4
+
+var secrets struct {
+	sync.RWMutex
+	values []string
+	// Keys of the arguments declared as sensitive
+	keys []string
+}
+
+// registerSecret marks a value that must never appear in error messages and log lines.
+func registerSecret(value string) {
+	if value == "" {
+		return
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	if !slices.Contains(secrets.values, value) {
+		secrets.values = append(secrets.values, value)
+	}
+}
+
+// registerSensitiveKey marks an argument whose value is redacted from logs and reports.
+func registerSensitiveKey(key string) {
+	key = strings.ToLower(key)
+
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	if !slices.Contains(secrets.keys, key) {
+		secrets.keys = append(secrets.keys, key)
+	}
+}
+
+// redactSecrets replaces the registered secrets embedded in free text, such as errors and log messages.
+func redactSecrets(text string) string {
+	secrets.RLock()
+	defer secrets.RUnlock()
+
+	for _, secret := range secrets.values {
+		if len(secret) >= MIN_EMBEDDED_SECRET_LENGTH {
+			text = strings.ReplaceAll(text, secret, REDACTED)
+		}
+	}
+
+	return text
+}
+
+// isSensitiveKey recognizes the sensitive arguments, credential headers and session tokens.
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+
+	secrets.RLock()
+	registered := slices.Contains(secrets.keys, key)
+	secrets.RUnlock()
+
+	return registered || strings.Contains(key, "password") || strings.Contains(key, "secret") || strings.Contains(key, "token") || key == "authorization"
+}
+
+// redactArguments hides the values of the sensitive arguments - other arguments are left as they are.
+func redactArguments(args map[string]string) map[string]string {
+	redacted := make(map[string]string, len(args))
+	for key, value := range args {
+		if value != "" && isSensitiveKey(key) {
+			redacted[key] = REDACTED
+		} else {
+			redacted[key] = value
+		}
+	}
+
+	return redacted
+}
+
+// redacted returns a copy of the result with the registered secrets removed from the errors and the sensitive facts hidden.
+func (r CheckResult) redacted() CheckResult {
+	r.Error = redactSecrets(r.Error)
+
+	if r.Facts != nil {
+		r.Facts = redactArguments(r.Facts)
+	}
+
+	if r.Attempts != nil {
+		attempts := slices.Clone(r.Attempts)
+		for index := range attempts {
+			attempts[index].Error = redactSecrets(attempts[index].Error)
+		}
+		r.Attempts = attempts
+	}
+
+	return r
+}
+
+// RedactingHandler removes the registered secrets from log records before passing them on.
+type RedactingHandler struct {
+	handler slog.Handler
+}
+
+func NewRedactingHandler(handler slog.Handler) *RedactingHandler {
+	return &RedactingHandler{handler: handler}
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		redacted = append(redacted, redactAttr(attr))
+	}
+
+	return &RedactingHandler{handler: h.handler.WithAttrs(redacted)}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{handler: h.handler.WithGroup(name)}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	record := slog.NewRecord(r.Time, r.Level, redactSecrets(r.Message), r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		record.AddAttrs(redactAttr(attr))
+		return true
+	})
+
+	return h.handler.Handle(ctx, record)
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	if isSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, REDACTED)
+	}
+
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, redactSecrets(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, 0, len(group))
+		for _, member := range group {
+			redacted = append(redacted, redactAttr(member))
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case map[string]string:
+			return slog.Any(attr.Key, redactArguments(v))
+		case error:
+			return slog.String(attr.Key, redactSecrets(v.Error()))
+		}
+	}
+
+	return attr
+}
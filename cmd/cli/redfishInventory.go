@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// redfishInventory holds the facts MetalSoft relies on when managing a server through its BMC.
+type redfishInventory struct {
+	RedfishVersion string
+	Vendor         string
+	Manufacturer   string
+	Model          string
+	SerialNumber   string
+	BIOSVersion    string
+	PowerState     string
+	BMCModel       string
+	BMCFirmware    string
+	BootTargets    []string
+	Role           string
+	UpdateService  bool
+	SessionService bool
+	Chassis        int
+}
+
+// Boot override targets used for provisioning, at least one of them is needed
+var requiredBootTargets = []string{"Pxe", "Cd", "UefiHttp"}
+
+// testRedfishInventory walks the Redfish service and reports the server inventory and missing capabilities.
+func (app *application) testRedfishInventory(ctx context.Context, hostname string, port int, username string, password string) (redfishInventory, CheckResult) {
+	slog.Debug(fmt.Sprintf("Testing Redfish inventory of %s:%d", hostname, port))
+
+	result := newCheckResult("RedfishInventory", hostname, port)
+
+	inventory, problems, warnings, err := app.collectRedfishInventory(ctx, hostname, port, username, password)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to read Redfish inventory of %s:%d - %s", hostname, port, err.Error()))
+		return inventory, result.fail(err)
+	}
+
+	result.addFact("RedfishVersion", inventory.RedfishVersion)
+	result.addFact("Vendor", inventory.Vendor)
+	result.addFact("Manufacturer", inventory.Manufacturer)
+	result.addFact("Model", inventory.Model)
+	result.addFact("SerialNumber", inventory.SerialNumber)
+	result.addFact("BiosVersion", inventory.BIOSVersion)
+	result.addFact("PowerState", inventory.PowerState)
+	result.addFact("BmcModel", inventory.BMCModel)
+	result.addFact("BmcFirmwareVersion", inventory.BMCFirmware)
+	result.addFact("BootSourceOverrideTargets", strings.Join(inventory.BootTargets, ","))
+	result.addFact("RoleId", inventory.Role)
+
+	if app.firmwarePolicy != nil {
+		rule, policyProblems, policyWarnings := app.firmwarePolicy.evaluate(inventory)
+		result.addFact("FirmwarePolicy", rule)
+		problems = append(problems, policyProblems...)
+		warnings = append(warnings, policyWarnings...)
+	}
+
+	slog.Debug(fmt.Sprintf("Redfish inventory of %s:%d\n  Model: %s %s\n  BIOS: %s\n  BMC: %s %s\n  Power: %s\n  Role: %s",
+		hostname, port, inventory.Manufacturer, inventory.Model, inventory.BIOSVersion, inventory.BMCModel, inventory.BMCFirmware, inventory.PowerState, inventory.Role))
+
+	for _, warning := range warnings {
+		slog.Warn(fmt.Sprintf("Redfish service on %s:%d - %s", hostname, port, warning))
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			slog.Error(fmt.Sprintf("Redfish service on %s:%d - %s", hostname, port, problem))
+		}
+		result = result.fail(errors.New(strings.Join(problems, "; ")))
+		result.ErrorClass = "capability"
+		return inventory, result
+	}
+
+	if len(warnings) > 0 {
+		result = result.warn(errors.New(strings.Join(warnings, "; ")))
+		result.ErrorClass = "capability"
+		return inventory, result
+	}
+
+	return inventory, result.pass()
+}
+
+// collectRedfishInventory reads the Redfish resources and sorts what is missing into problems and warnings.
+func (app *application) collectRedfishInventory(ctx context.Context, hostname string, port int, username string, password string) (redfishInventory, []string, []string, error) {
+	inventory := redfishInventory{}
+	problems := []string{}
+	warnings := []string{}
+
+	ctx, logout, err := app.redfishLogin(ctx, hostname, port, username, password)
+	if err != nil {
+		return inventory, nil, nil, err
+	}
+	defer logout()
+
+	root, err := app.getRedfishResource(ctx, hostname, port, username, password, "/redfish/v1")
+	if err != nil {
+		return inventory, nil, nil, err
+	}
+	inventory.RedfishVersion = safeConvert(root, "RedfishVersion")
+	inventory.Vendor = safeConvert(root, "Vendor")
+
+	// Systems - model, BIOS, power state and boot override
+	if system := app.firstRedfishMember(ctx, hostname, port, username, password, safeLink(root, "Systems")); system != nil {
+		inventory.Manufacturer = safeConvert(system, "Manufacturer")
+		inventory.Model = safeConvert(system, "Model")
+		inventory.SerialNumber = safeConvert(system, "SerialNumber")
+		inventory.BIOSVersion = safeConvert(system, "BiosVersion")
+		inventory.PowerState = safeConvert(system, "PowerState")
+		inventory.BootTargets = safeStrings(safeMap(system, "Boot"), "BootSourceOverrideTarget@Redfish.AllowableValues")
+
+		if len(inventory.BootTargets) == 0 {
+			problems = append(problems, "boot source override is not supported")
+		} else if !slices.ContainsFunc(requiredBootTargets, func(target string) bool { return slices.Contains(inventory.BootTargets, target) }) {
+			problems = append(problems, fmt.Sprintf("boot source override does not support any of %s", strings.Join(requiredBootTargets, ", ")))
+		}
+		if inventory.PowerState == "" {
+			problems = append(problems, "power state is not reported")
+		}
+	} else {
+		problems = append(problems, "no computer system found")
+	}
+
+	// Managers - BMC model and firmware
+	if manager := app.firstRedfishMember(ctx, hostname, port, username, password, safeLink(root, "Managers")); manager != nil {
+		inventory.BMCModel = safeConvert(manager, "Model")
+		inventory.BMCFirmware = safeConvert(manager, "FirmwareVersion")
+	} else {
+		problems = append(problems, "no manager found")
+	}
+
+	// Chassis
+	if chassisURI := safeLink(root, "Chassis"); chassisURI != "" {
+		if chassis, err := app.getRedfishResource(ctx, hostname, port, username, password, chassisURI); err == nil {
+			inventory.Chassis = len(safeMembers(chassis))
+		}
+	}
+	if inventory.Chassis == 0 {
+		warnings = append(warnings, "no chassis found")
+	}
+
+	// UpdateService - firmware updates
+	if updateURI := safeLink(root, "UpdateService"); updateURI != "" {
+		if update, err := app.getRedfishResource(ctx, hostname, port, username, password, updateURI); err == nil {
+			inventory.UpdateService = safeBool(update, "ServiceEnabled")
+		}
+	}
+	if !inventory.UpdateService {
+		warnings = append(warnings, "update service is not available")
+	}
+
+	// SessionService - token authentication
+	if sessionURI := safeLink(root, "SessionService"); sessionURI != "" {
+		if session, err := app.getRedfishResource(ctx, hostname, port, username, password, sessionURI); err == nil {
+			inventory.SessionService = safeBool(session, "ServiceEnabled")
+		}
+	}
+	if !inventory.SessionService {
+		warnings = append(warnings, "session service is not available")
+	}
+
+	// AccountService - role of the user
+	inventory.Role, err = app.redfishAccountRole(ctx, hostname, port, username, password, safeLink(root, "AccountService"))
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("could not determine the role of user %s - %s", username, err.Error()))
+	} else if inventory.Role != "Administrator" {
+		problems = append(problems, fmt.Sprintf("user %s has role %s - Administrator is required", username, inventory.Role))
+	}
+
+	return inventory, problems, warnings, nil
+}
+
+// firstRedfishMember returns the first member of a Redfish collection.
+func (app *application) firstRedfishMember(ctx context.Context, hostname string, port int, username string, password string, collectionURI string) interface{} {
+	if collectionURI == "" {
+		return nil
+	}
+
+	collection, err := app.getRedfishResource(ctx, hostname, port, username, password, collectionURI)
+	if err != nil {
+		return nil
+	}
+
+	members := safeMembers(collection)
+	if len(members) == 0 {
+		return nil
+	}
+
+	member, err := app.getRedfishResource(ctx, hostname, port, username, password, members[0])
+	if err != nil {
+		return nil
+	}
+
+	return member
+}
+
+// redfishAccountRole looks up the role of the user in the account service.
+func (app *application) redfishAccountRole(ctx context.Context, hostname string, port int, username string, password string, accountServiceURI string) (string, error) {
+	if accountServiceURI == "" {
+		return "", errors.New("the account service is not available")
+	}
+
+	accountService, err := app.getRedfishResource(ctx, hostname, port, username, password, accountServiceURI)
+	if err != nil {
+		return "", fmt.Errorf("the account service is not readable - %s", err.Error())
+	}
+
+	accounts, err := app.getRedfishResource(ctx, hostname, port, username, password, safeLink(accountService, "Accounts"))
+	if err != nil {
+		return "", fmt.Errorf("the accounts are not readable - %s", err.Error())
+	}
+
+	for _, accountURI := range safeMembers(accounts) {
+		account, err := app.getRedfishResource(ctx, hostname, port, username, password, accountURI)
+		if err != nil {
+			continue
+		}
+		if safeConvert(account, "UserName") == username {
+			return safeConvert(account, "RoleId"), nil
+		}
+	}
+
+	return "", errors.New("the account was not found")
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resources of the emulated iDRAC9
+const (
+	MOCK_REDFISH_SYSTEM  = "/redfish/v1/Systems/System.Embedded.1"
+	MOCK_REDFISH_MANAGER = "/redfish/v1/Managers/iDRAC.Embedded.1"
+	MOCK_REDFISH_CHASSIS = "/redfish/v1/Chassis/System.Embedded.1"
+	MOCK_REDFISH_MEDIA   = MOCK_REDFISH_MANAGER + "/VirtualMedia"
+	MOCK_REDFISH_ACCOUNT = "/redfish/v1/AccountService/Accounts/2"
+)
+
+type redfishJSON map[string]interface{}
+
+func redfishLinkTo(uri string) redfishJSON {
+	return redfishJSON{"@odata.id": uri}
+}
+
+func redfishCollection(uri string, members ...string) redfishJSON {
+	links := []redfishJSON{}
+	for _, member := range members {
+		links = append(links, redfishLinkTo(member))
+	}
+
+	return redfishJSON{
+		"@odata.id":           uri,
+		"Members":             links,
+		"Members@odata.count": len(links),
+	}
+}
+
+func (app *application) startRedfishServer(ctx context.Context, ip netip.Addr, port uint16, bmc *mockBMC) {
+	defer app.wg.Done()
+
+	address := netip.AddrPortFrom(ip, port).String()
+
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error starting Redfish server on %s - %s", address, err.Error()))
+		return
+	}
+
+	srv := &http.Server{
+		Addr:         address,
+		Handler:      http.HandlerFunc(bmc.redfishRequestHandler),
+		TLSConfig:    tlsConfig,
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		slog.Info(fmt.Sprintf("Shutting down Redfish server on %s", address))
+
+		if err := srv.Shutdown(ctxShutdown); err != nil {
+			slog.Error(fmt.Sprintf("Error shutting down Redfish server on %s - %s", address, err.Error()))
+		}
+	}()
+
+	slog.Info(fmt.Sprintf("Starting Redfish server on %s", address))
+
+	err = srv.ListenAndServeTLS("", "")
+	if !errors.Is(err, http.ErrServerClosed) {
+		slog.Error(fmt.Sprintf("Error starting Redfish server on %s - %s", address, err.Error()))
+		return
+	}
+
+	slog.Info(fmt.Sprintf("Redfish server on %s shut down", address))
+}
+
+func (bmc *mockBMC) redfishRequestHandler(w http.ResponseWriter, r *http.Request) {
+	slog.Debug(fmt.Sprintf("Redfish request received from %s: %s %s", r.RemoteAddr, r.Method, r.URL.Path))
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+
+	// The service root is readable and sessions can be created without authentication
+	public := path == "/redfish" || path == "/redfish/v1" || (r.Method == http.MethodPost && path == DEFAULT_REDFISH_SESSIONS_URI)
+	if !public && !bmc.redfishAuthorized(r) {
+		slog.Debug(fmt.Sprintf("Redfish request from %s rejected - invalid credentials", r.RemoteAddr))
+		writeRedfishError(w, http.StatusUnauthorized, "Base.1.12.NoValidSession", "There is no valid session established with the implementation.")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet:
+		resource := bmc.redfishResource(path)
+		if resource == nil {
+			writeRedfishError(w, http.StatusNotFound, "Base.1.12.ResourceMissingAtURI", fmt.Sprintf("The resource at the URI %s was not found.", path))
+			return
+		}
+		writeRedfishJSON(w, http.StatusOK, resource)
+
+	case r.Method == http.MethodPost && path == DEFAULT_REDFISH_SESSIONS_URI:
+		bmc.redfishCreateSession(w, r)
+
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, DEFAULT_REDFISH_SESSIONS_URI+"/"):
+		bmc.redfishDeleteSession(w, path)
+
+	case r.Method == http.MethodPost && path == MOCK_REDFISH_SYSTEM+"/Actions/ComputerSystem.Reset":
+		bmc.redfishReset(w, r)
+
+	case r.Method == http.MethodPost && path == MOCK_REDFISH_MEDIA+"/CD/Actions/VirtualMedia.InsertMedia":
+		bmc.redfishInsertMedia(w, r)
+
+	case r.Method == http.MethodPost && path == MOCK_REDFISH_MEDIA+"/CD/Actions/VirtualMedia.EjectMedia":
+		bmc.setMediaImage("")
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPatch && path == MOCK_REDFISH_MEDIA+"/CD":
+		bmc.redfishPatchMedia(w, r)
+
+	default:
+		writeRedfishError(w, http.StatusMethodNotAllowed, "Base.1.12.OperationNotAllowed", fmt.Sprintf("The %s operation is not allowed on %s.", r.Method, path))
+	}
+}
+
+// redfishAuthorized accepts a session token or basic authentication.
+func (bmc *mockBMC) redfishAuthorized(r *http.Request) bool {
+	bmc.mu.Lock()
+	defer bmc.mu.Unlock()
+
+	if token := r.Header.Get("X-Auth-Token"); token != "" {
+		_, ok := bmc.sessions[token]
+		return ok
+	}
+
+	username, password, ok := r.BasicAuth()
+	return ok && username == bmc.username && password == bmc.password
+}
+
+func (bmc *mockBMC) redfishCreateSession(w http.ResponseWriter, r *http.Request) {
+	credentials := struct {
+		UserName string
+		Password string
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		writeRedfishError(w, http.StatusBadRequest, "Base.1.12.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+
+	if credentials.UserName != bmc.username || credentials.Password != bmc.password {
+		slog.Debug(fmt.Sprintf("Redfish login of %s from %s rejected", credentials.UserName, r.RemoteAddr))
+		writeRedfishError(w, http.StatusUnauthorized, "Base.1.12.InsufficientPrivilege", "The authentication credentials included with this request are missing or invalid.")
+		return
+	}
+
+	token := make([]byte, 16)
+	rand.Read(token)
+
+	bmc.mu.Lock()
+	bmc.sessionID++
+	id := strconv.Itoa(bmc.sessionID)
+	bmc.sessions[hex.EncodeToString(token)] = id
+	bmc.mu.Unlock()
+
+	location := DEFAULT_REDFISH_SESSIONS_URI + "/" + id
+	slog.Debug(fmt.Sprintf("Redfish session %s created for %s from %s", location, credentials.UserName, r.RemoteAddr))
+
+	w.Header().Set("X-Auth-Token", hex.EncodeToString(token))
+	w.Header().Set("Location", location)
+	writeRedfishJSON(w, http.StatusCreated, bmc.redfishResource(location))
+}
+
+func (bmc *mockBMC) redfishDeleteSession(w http.ResponseWriter, path string) {
+	bmc.mu.Lock()
+	defer bmc.mu.Unlock()
+
+	id := path[strings.LastIndex(path, "/")+1:]
+	for token, sessionID := range bmc.sessions {
+		if sessionID == id {
+			delete(bmc.sessions, token)
+			slog.Debug(fmt.Sprintf("Redfish session %s deleted", path))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	writeRedfishError(w, http.StatusNotFound, "Base.1.12.ResourceMissingAtURI", fmt.Sprintf("The resource at the URI %s was not found.", path))
+}
+
+func (bmc *mockBMC) redfishReset(w http.ResponseWriter, r *http.Request) {
+	request := struct {
+		ResetType string
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeRedfishError(w, http.StatusBadRequest, "Base.1.12.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+
+	switch request.ResetType {
+	case "On", "ForceOn", "ForceRestart", "GracefulRestart", "PowerCycle":
+		bmc.setPower(true)
+	case "ForceOff", "GracefulShutdown":
+		bmc.setPower(false)
+	default:
+		writeRedfishError(w, http.StatusBadRequest, "Base.1.12.PropertyValueNotInList", fmt.Sprintf("The value '%s' for the property ResetType is not in the list of acceptable values.", request.ResetType))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (bmc *mockBMC) redfishInsertMedia(w http.ResponseWriter, r *http.Request) {
+	request := struct {
+		Image string
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeRedfishError(w, http.StatusBadRequest, "Base.1.12.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+
+	if request.Image == "" {
+		writeRedfishError(w, http.StatusBadRequest, "Base.1.12.PropertyMissing", "The property Image is a required property and must be included in the request.")
+		return
+	}
+
+	bmc.setMediaImage(request.Image)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// redfishPatchMedia mounts and unmounts the media on update, as older BMCs without the InsertMedia action do.
+func (bmc *mockBMC) redfishPatchMedia(w http.ResponseWriter, r *http.Request) {
+	request := struct {
+		Image    *string
+		Inserted *bool
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeRedfishError(w, http.StatusBadRequest, "Base.1.12.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+
+	if request.Image == nil || *request.Image == "" || (request.Inserted != nil && !*request.Inserted) {
+		bmc.setMediaImage("")
+	} else {
+		bmc.setMediaImage(*request.Image)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (bmc *mockBMC) setMediaImage(image string) {
+	bmc.mu.Lock()
+	defer bmc.mu.Unlock()
+
+	if image == "" {
+		slog.Info("Mock BMC virtual media ejected")
+	} else {
+		slog.Info(fmt.Sprintf("Mock BMC virtual media inserted %s", image))
+	}
+	bmc.mediaImage = image
+}
+
+// redfishResource returns the resource at the path, or nil if there is none.
+func (bmc *mockBMC) redfishResource(path string) redfishJSON {
+	bmc.mu.Lock()
+	defer bmc.mu.Unlock()
+
+	powerState := formatPowerState(bmc.powerOn)
+
+	switch path {
+	case "/redfish":
+		return redfishJSON{"v1": "/redfish/v1/"}
+
+	case "/redfish/v1":
+		return redfishJSON{
+			"@odata.id":      "/redfish/v1",
+			"Id":             "RootService",
+			"Name":           "Root Service",
+			"RedfishVersion": "1.17.0",
+			"Vendor":         "Dell",
+			"Product":        "Integrated Dell Remote Access Controller",
+			"Systems":        redfishLinkTo("/redfish/v1/Systems"),
+			"Managers":       redfishLinkTo("/redfish/v1/Managers"),
+			"Chassis":        redfishLinkTo("/redfish/v1/Chassis"),
+			"UpdateService":  redfishLinkTo("/redfish/v1/UpdateService"),
+			"SessionService": redfishLinkTo("/redfish/v1/SessionService"),
+			"AccountService": redfishLinkTo("/redfish/v1/AccountService"),
+			"Links": redfishJSON{
+				"Sessions": redfishLinkTo(DEFAULT_REDFISH_SESSIONS_URI),
+			},
+		}
+
+	case "/redfish/v1/Systems":
+		return redfishCollection(path, MOCK_REDFISH_SYSTEM)
+
+	case MOCK_REDFISH_SYSTEM:
+		return redfishJSON{
+			"@odata.id":    path,
+			"Id":           "System.Embedded.1",
+			"Manufacturer": "Dell Inc.",
+			"Model":        "PowerEdge R650",
+			"SerialNumber": "MOCKBMC01",
+			"SKU":          "MOCK001",
+			"BiosVersion":  "1.10.2",
+			"PowerState":   powerState,
+			"Boot": redfishJSON{
+				"BootSourceOverrideEnabled": "Disabled",
+				"BootSourceOverrideTarget":  "None",
+				"BootSourceOverrideTarget@Redfish.AllowableValues": []string{
+					"None", "Pxe", "Floppy", "Cd", "Hdd", "BiosSetup", "Utilities", "UefiTarget", "SDCard", "UefiHttp",
+				},
+			},
+			"Actions": redfishJSON{
+				"#ComputerSystem.Reset": redfishJSON{
+					"target": MOCK_REDFISH_SYSTEM + "/Actions/ComputerSystem.Reset",
+					"ResetType@Redfish.AllowableValues": []string{
+						"On", "ForceOff", "ForceRestart", "GracefulRestart", "GracefulShutdown", "PowerCycle",
+					},
+				},
+			},
+		}
+
+	case "/redfish/v1/Managers":
+		return redfishCollection(path, MOCK_REDFISH_MANAGER)
+
+	case MOCK_REDFISH_MANAGER:
+		return redfishJSON{
+			"@odata.id":       path,
+			"Id":              "iDRAC.Embedded.1",
+			"ManagerType":     "BMC",
+			"Model":           "15G Monolithic",
+			"FirmwareVersion": "6.10.30.00",
+			"UUID":            formatMockGUID(bmc.guid),
+			"VirtualMedia":    redfishLinkTo(MOCK_REDFISH_MEDIA),
+		}
+
+	case MOCK_REDFISH_MEDIA:
+		return redfishCollection(path, MOCK_REDFISH_MEDIA+"/CD", MOCK_REDFISH_MEDIA+"/RemovableDisk")
+
+	case MOCK_REDFISH_MEDIA + "/CD":
+		return redfishJSON{
+			"@odata.id":      path,
+			"Id":             "CD",
+			"MediaTypes":     []string{"CD", "DVD"},
+			"Image":          nullableString(bmc.mediaImage),
+			"Inserted":       bmc.mediaImage != "",
+			"WriteProtected": true,
+			"Actions": redfishJSON{
+				"#VirtualMedia.InsertMedia": redfishJSON{"target": path + "/Actions/VirtualMedia.InsertMedia"},
+				"#VirtualMedia.EjectMedia":  redfishJSON{"target": path + "/Actions/VirtualMedia.EjectMedia"},
+			},
+		}
+
+	case MOCK_REDFISH_MEDIA + "/RemovableDisk":
+		return redfishJSON{
+			"@odata.id":  path,
+			"Id":         "RemovableDisk",
+			"MediaTypes": []string{"USBStick"},
+			"Image":      nil,
+			"Inserted":   false,
+		}
+
+	case "/redfish/v1/Chassis":
+		return redfishCollection(path, MOCK_REDFISH_CHASSIS)
+
+	case MOCK_REDFISH_CHASSIS:
+		return redfishJSON{
+			"@odata.id":   path,
+			"Id":          "System.Embedded.1",
+			"ChassisType": "RackMount",
+			"PowerState":  powerState,
+		}
+
+	case "/redfish/v1/UpdateService":
+		return redfishJSON{
+			"@odata.id":      path,
+			"Id":             "UpdateService",
+			"ServiceEnabled": true,
+		}
+
+	case "/redfish/v1/SessionService":
+		return redfishJSON{
+			"@odata.id":      path,
+			"Id":             "SessionService",
+			"ServiceEnabled": true,
+			"SessionTimeout": 1800,
+			"Sessions":       redfishLinkTo(DEFAULT_REDFISH_SESSIONS_URI),
+		}
+
+	case DEFAULT_REDFISH_SESSIONS_URI:
+		members := []string{}
+		for _, id := range bmc.sessions {
+			members = append(members, DEFAULT_REDFISH_SESSIONS_URI+"/"+id)
+		}
+		slices.Sort(members)
+		return redfishCollection(path, members...)
+
+	case "/redfish/v1/AccountService":
+		return redfishJSON{
+			"@odata.id":      path,
+			"Id":             "AccountService",
+			"ServiceEnabled": true,
+			"Accounts":       redfishLinkTo("/redfish/v1/AccountService/Accounts"),
+		}
+
+	case "/redfish/v1/AccountService/Accounts":
+		return redfishCollection(path, MOCK_REDFISH_ACCOUNT)
+
+	case MOCK_REDFISH_ACCOUNT:
+		return redfishJSON{
+			"@odata.id": path,
+			"Id":        "2",
+			"UserName":  bmc.username,
+			"RoleId":    "Administrator",
+			"Enabled":   true,
+			"Locked":    false,
+		}
+	}
+
+	if id, ok := strings.CutPrefix(path, DEFAULT_REDFISH_SESSIONS_URI+"/"); ok {
+		for _, sessionID := range bmc.sessions {
+			if sessionID == id {
+				return redfishJSON{
+					"@odata.id": path,
+					"Id":        id,
+					"UserName":  bmc.username,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeRedfishJSON(w http.ResponseWriter, status int, resource redfishJSON) {
+	w.Header().Set("Content-Type", "application/json;odata.metadata=minimal;charset=utf-8")
+	w.Header().Set("OData-Version", "4.0")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(resource); err != nil {
+		slog.Error(fmt.Sprintf("Error writing Redfish response - %s", err.Error()))
+	}
+}
+
+func writeRedfishError(w http.ResponseWriter, status int, code string, message string) {
+	writeRedfishJSON(w, status, redfishJSON{
+		"error": redfishJSON{
+			"code":    code,
+			"message": message,
+			"@Message.ExtendedInfo": []redfishJSON{
+				{"MessageId": code, "Message": message},
+			},
+		},
+	})
+}
+
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// formatMockGUID formats the GUID of the mock BMC as UUID.
+func formatMockGUID(guid [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", guid[0:4], guid[4:6], guid[6:8], guid[8:10], guid[10:16])
+}
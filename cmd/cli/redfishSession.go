@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const DEFAULT_REDFISH_SESSIONS_URI = "/redfish/v1/SessionService/Sessions"
+
+var errAccountLocked = errors.New("account locked")
+
+// redfishSession is a token obtained from the Redfish SessionService, used instead of basic authentication.
+type redfishSession struct {
+	token    string
+	location string
+}
+
+type redfishSessionKey struct{}
+
+func withRedfishSession(ctx context.Context, session *redfishSession) context.Context {
+	return context.WithValue(ctx, redfishSessionKey{}, session)
+}
+
+func redfishSessionFrom(ctx context.Context) *redfishSession {
+	session, _ := ctx.Value(redfishSessionKey{}).(*redfishSession)
+	return session
+}
+
+// redfishLogin opens a session used by the Redfish requests made with the returned context.
+// BMCs without session support fall back to basic authentication. Only rejected credentials are returned as error,
+// so callers stop before repeated failed logins lock the account.
+func (app *application) redfishLogin(ctx context.Context, hostname string, port int, username string, password string) (context.Context, func(), error) {
+	session, err := app.createRedfishSession(ctx, hostname, port, username, password)
+	if err != nil {
+		if isRedfishAuthError(err) {
+			return ctx, func() {}, err
+		}
+		slog.Debug(fmt.Sprintf("Redfish sessions not available on %s:%d, using basic authentication - %s", hostname, port, err.Error()))
+		return ctx, func() {}, nil
+	}
+
+	logout := func() {
+		app.deleteRedfishSession(ctx, hostname, port, session)
+	}
+
+	return withRedfishSession(ctx, session), logout, nil
+}
+
+// createRedfishSession logs in through the SessionService.
+func (app *application) createRedfishSession(ctx context.Context, hostname string, port int, username string, password string) (*redfishSession, error) {
+	sessionsURI := DEFAULT_REDFISH_SESSIONS_URI
+
+	// The service root is readable without authentication and links the sessions collection
+	if root, err := app.getRedfishResource(ctx, hostname, port, "", "", "/redfish/v1"); err == nil {
+		if link := safeLink(safeMap(root, "Links"), "Sessions"); link != "" {
+			sessionsURI = link
+		}
+	}
+
+	response, err := app.redfishRequest(ctx, http.MethodPost, hostname, port, "", "", sessionsURI, map[string]string{
+		"UserName": username,
+		"Password": password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode() != http.StatusCreated && response.StatusCode() != http.StatusOK {
+		return nil, redfishStatusError(response)
+	}
+
+	session := &redfishSession{
+		token:    response.Header().Get("X-Auth-Token"),
+		location: response.Header().Get("Location"),
+	}
+	if session.token == "" {
+		return nil, errors.New("session created without X-Auth-Token")
+	}
+
+	// Some BMCs return an absolute URL in the Location header
+	if parsed, err := url.Parse(session.location); err == nil && parsed.Path != "" {
+		session.location = parsed.Path
+	}
+
+	registerSecret(session.token)
+	slog.Debug(fmt.Sprintf("Created Redfish session %s on %s:%d", session.location, hostname, port))
+
+	return session, nil
+}
+
+// deleteRedfishSession logs out, as BMCs allow only a few concurrent sessions.
+func (app *application) deleteRedfishSession(ctx context.Context, hostname string, port int, session *redfishSession) {
+	if session.location == "" {
+		slog.Warn(fmt.Sprintf("Redfish session on %s:%d has no location - it will remain open until it expires", hostname, port))
+		return
+	}
+
+	// The session is closed even if the check was canceled
+	ctx = withRedfishSession(context.WithoutCancel(ctx), session)
+
+	err := app.redfishAction(ctx, http.MethodDelete, hostname, port, "", "", session.location, nil)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to delete Redfish session %s on %s:%d - %s", session.location, hostname, port, err.Error()))
+		return
+	}
+
+	slog.Debug(fmt.Sprintf("Deleted Redfish session %s on %s:%d", session.location, hostname, port))
+}
+
+// redfishAuthMethod tests which authentication method the BMC accepts on the systems collection,
+// as the service root is readable without authentication. Basic authentication is only tried when
+// redfishLogin found sessions unsupported, as on BMCs that disable it every attempt counts toward the account lockout.
+func (app *application) redfishAuthMethod(ctx context.Context, hostname string, port int, username string, password string) (string, error) {
+	systemsURI := "/redfish/v1/Systems"
+	if root, err := app.getRedfishResource(ctx, hostname, port, "", "", "/redfish/v1"); err == nil && safeLink(root, "Systems") != "" {
+		systemsURI = safeLink(root, "Systems")
+	}
+
+	if redfishSessionFrom(ctx) != nil {
+		if _, err := app.getRedfishResource(ctx, hostname, port, "", "", systemsURI); err != nil {
+			return "", err
+		}
+		return "session", nil
+	}
+
+	if _, err := app.getRedfishResource(withRedfishSession(ctx, nil), hostname, port, username, password, systemsURI); err != nil {
+		return "", err
+	}
+
+	return "basic", nil
+}
+
+// redfishStatusError describes a failed Redfish response, recognizing locked accounts from the error messages.
+func redfishStatusError(response *resty.Response) error {
+	err := &statusError{code: response.StatusCode(), status: response.Status()}
+
+	switch response.StatusCode() {
+	case http.StatusLocked:
+		return fmt.Errorf("%w - %w", err, errAccountLocked)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		body := strings.ToLower(string(response.Body()))
+		if strings.Contains(body, "locked") || strings.Contains(body, "lockout") {
+			return fmt.Errorf("%w - %w", err, errAccountLocked)
+		}
+	}
+
+	return err
+}
+
+func isRedfishAuthError(err error) bool {
+	switch classifyError(err) {
+	case "auth", "forbidden", "account-locked":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	VIRTUAL_MEDIA_TIMEOUT       = 2 * time.Minute
+	VIRTUAL_MEDIA_POLL_INTERVAL = 3 * time.Second
+)
+
+// virtualMediaVendor lists where a vendor's BMC exposes the virtual CD/DVD drive.
+type virtualMediaVendor struct {
+	// Resources holding a VirtualMedia collection, tried before the discovered ones
+	resources []string
+	// Preferred members of the VirtualMedia collection
+	media []string
+}
+
+var virtualMediaVendors = map[string]virtualMediaVendor{
+	// iDRAC 9 before firmware 6.x exposes the media under the manager, later versions under the system
+	"dell": {
+		resources: []string{"/redfish/v1/Managers/iDRAC.Embedded.1", "/redfish/v1/Systems/System.Embedded.1"},
+		media:     []string{"CD", "1"},
+	},
+	// iLO 5/6 - member 1 is the floppy/USB key, member 2 the CD/DVD
+	"hp": {
+		resources: []string{"/redfish/v1/Managers/1"},
+		media:     []string{"2"},
+	},
+	// XCC remote mounts
+	"lenovo": {
+		resources: []string{"/redfish/v1/Managers/1"},
+		media:     []string{"EXT1", "EXT2", "EXT3", "EXT4"},
+	},
+}
+
+// testRedfishVirtualMedia mounts the ISO as virtual CD/DVD, waits for the BMC to report it inserted and ejects it.
+func (app *application) testRedfishVirtualMedia(ctx context.Context, hostname string, port int, username string, password string, vendor string, isoLink string) CheckResult {
+	slog.Debug(fmt.Sprintf("Testing Redfish virtual media on %s:%d with %s", hostname, port, isoLink))
+
+	result := newCheckResult("VirtualMedia", hostname, port)
+	result.addFact("image", isoLink)
+
+	ctx, logout, err := app.redfishLogin(ctx, hostname, port, username, password)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to log in to Redfish on %s:%d - %s", hostname, port, err.Error()))
+		return result.fail(err)
+	}
+	defer logout()
+
+	mediaURI, media, err := app.findRedfishVirtualMedia(ctx, hostname, port, username, password, strings.ToLower(vendor))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to find virtual media on %s:%d - %s", hostname, port, err.Error()))
+		return result.fail(err)
+	}
+	result.addFact("media", mediaURI)
+
+	if safeBool(media, "Inserted") {
+		slog.Warn(fmt.Sprintf("Virtual media %s on %s:%d already has %s inserted - not testing", mediaURI, hostname, port, safeConvert(media, "Image")))
+		return result.skip("virtual media already in use")
+	}
+
+	err = app.insertRedfishVirtualMedia(ctx, hostname, port, username, password, mediaURI, media, isoLink)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to insert virtual media %s on %s:%d - %s", mediaURI, hostname, port, err.Error()))
+		return result.fail(err)
+	}
+
+	started := time.Now()
+	err = app.waitRedfishVirtualMedia(ctx, hostname, port, username, password, mediaURI)
+	result.addFact("insert_duration", time.Since(started).Round(time.Millisecond).String())
+
+	// Always eject, the BMC may have partially mounted the image even if it never reported it inserted
+	ejectErr := app.ejectRedfishVirtualMedia(ctx, hostname, port, username, password, mediaURI, media)
+
+	if err != nil {
+		slog.Error(fmt.Sprintf("Virtual media %s on %s:%d did not mount %s - %s", mediaURI, hostname, port, isoLink, err.Error()))
+		return result.fail(err)
+	}
+
+	if ejectErr != nil {
+		slog.Warn(fmt.Sprintf("Failed to eject virtual media %s on %s:%d - %s", mediaURI, hostname, port, ejectErr.Error()))
+		return result.warn(ejectErr)
+	}
+
+	slog.Debug(fmt.Sprintf("Virtual media %s on %s:%d mounted and ejected %s", mediaURI, hostname, port, isoLink))
+
+	return result.pass()
+}
+
+// findRedfishVirtualMedia returns the URI and the state of the virtual CD/DVD drive.
+func (app *application) findRedfishVirtualMedia(ctx context.Context, hostname string, port int, username string, password string, vendor string) (string, interface{}, error) {
+	candidates := slices.Clone(virtualMediaVendors[vendor].resources)
+
+	for _, collection := range []string{"/redfish/v1/Managers", "/redfish/v1/Systems"} {
+		data, err := app.getRedfishResource(ctx, hostname, port, username, password, collection)
+		if err != nil {
+			continue
+		}
+		for _, member := range safeMembers(data) {
+			if !slices.Contains(candidates, member) {
+				candidates = append(candidates, member)
+			}
+		}
+	}
+
+	for _, resource := range candidates {
+		data, err := app.getRedfishResource(ctx, hostname, port, username, password, resource)
+		if err != nil {
+			continue
+		}
+
+		collectionURI := safeLink(data, "VirtualMedia")
+		if collectionURI == "" {
+			continue
+		}
+
+		collection, err := app.getRedfishResource(ctx, hostname, port, username, password, collectionURI)
+		if err != nil {
+			continue
+		}
+
+		members := safeMembers(collection)
+		slices.SortStableFunc(members, func(a, b string) int {
+			return mediaPreference(vendor, a) - mediaPreference(vendor, b)
+		})
+
+		for _, mediaURI := range members {
+			media, err := app.getRedfishResource(ctx, hostname, port, username, password, mediaURI)
+			if err != nil {
+				continue
+			}
+
+			mediaTypes := safeStrings(media, "MediaTypes")
+			if len(mediaTypes) == 0 || slices.Contains(mediaTypes, "CD") || slices.Contains(mediaTypes, "DVD") {
+				return mediaURI, media, nil
+			}
+		}
+	}
+
+	return "", nil, errors.New("no virtual CD/DVD media found")
+}
+
+// mediaPreference orders the members of a VirtualMedia collection by the vendor preferences.
+func mediaPreference(vendor string, mediaURI string) int {
+	id := mediaURI[strings.LastIndex(mediaURI, "/")+1:]
+
+	index := slices.Index(virtualMediaVendors[vendor].media, id)
+	if index == -1 {
+		return len(virtualMediaVendors[vendor].media)
+	}
+
+	return index
+}
+
+func (app *application) insertRedfishVirtualMedia(ctx context.Context, hostname string, port int, username string, password string, mediaURI string, media interface{}, isoLink string) error {
+	target := safeConvert(safeMap(safeMap(media, "Actions"), "#VirtualMedia.InsertMedia"), "target")
+	if target != "" {
+		return app.redfishAction(ctx, http.MethodPost, hostname, port, username, password, target, map[string]interface{}{
+			"Image":          isoLink,
+			"Inserted":       true,
+			"WriteProtected": true,
+		})
+	}
+
+	// BMCs without the InsertMedia action (e.g. older XCC firmware) mount on update of the resource
+	return app.redfishAction(ctx, http.MethodPatch, hostname, port, username, password, mediaURI, map[string]interface{}{
+		"Image":    isoLink,
+		"Inserted": true,
+	})
+}
+
+func (app *application) ejectRedfishVirtualMedia(ctx context.Context, hostname string, port int, username string, password string, mediaURI string, media interface{}) error {
+	target := safeConvert(safeMap(safeMap(media, "Actions"), "#VirtualMedia.EjectMedia"), "target")
+	if target != "" {
+		return app.redfishAction(ctx, http.MethodPost, hostname, port, username, password, target, map[string]interface{}{})
+	}
+
+	return app.redfishAction(ctx, http.MethodPatch, hostname, port, username, password, mediaURI, map[string]interface{}{
+		"Image":    nil,
+		"Inserted": false,
+	})
+}
+
+// waitRedfishVirtualMedia polls the media until the BMC reports it inserted.
+func (app *application) waitRedfishVirtualMedia(ctx context.Context, hostname string, port int, username string, password string, mediaURI string) error {
+	timedCtx, cancel := context.WithTimeout(ctx, VIRTUAL_MEDIA_TIMEOUT)
+	defer cancel()
+
+	for {
+		media, err := app.getRedfishResource(timedCtx, hostname, port, username, password, mediaURI)
+		if err == nil && safeBool(media, "Inserted") {
+			return nil
+		}
+
+		select {
+		case <-timedCtx.Done():
+			return fmt.Errorf("media not inserted after %s", VIRTUAL_MEDIA_TIMEOUT)
+		case <-time.After(VIRTUAL_MEDIA_POLL_INTERVAL):
+		}
+	}
+}
+
+// redfishAction sends a state changing request and checks it was accepted.
+func (app *application) redfishAction(ctx context.Context, method string, hostname string, port int, username string, password string, uri string, body interface{}) error {
+	response, err := app.redfishRequest(ctx, method, hostname, port, username, password, uri, body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode() >= http.StatusBadRequest {
+		slog.Debug(fmt.Sprintf("Redfish %s %s returned %s:\n%s", method, uri, response.Status(), string(response.Body())))
+		return redfishStatusError(response)
+	}
+
+	slog.Debug(fmt.Sprintf("Redfish %s %s returned %s", method, uri, response.Status()))
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"time"
+)
+
+type toolInfo struct {
+	Version   string `json:"version"`
+	GitHash   string `json:"githash"`
+	BuildDate string `json:"builddate"`
+}
+
+type hostInfo struct {
+	Hostname   string              `json:"hostname"`
+	OS         string              `json:"os"`
+	Arch       string              `json:"arch"`
+	CPUs       int                 `json:"cpus"`
+	Interfaces map[string][]string `json:"interfaces,omitempty"`
+}
+
+type reportSummary struct {
+	Total int `json:"total"`
+	Pass  int `json:"pass"`
+	Warn  int `json:"warn"`
+	Fail  int `json:"fail"`
+	Skip  int `json:"skip"`
+}
+
+type checkReport struct {
+	Tool       toolInfo          `json:"tool"`
+	Host       hostInfo          `json:"host"`
+	Command    string            `json:"command"`
+	Arguments  map[string]string `json:"arguments"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Summary    reportSummary     `json:"summary"`
+	Results    checkResults      `json:"results"`
+}
+
+func newCheckReport(config runtimeConfiguration, results checkResults, startedAt time.Time) checkReport {
+	redacted := make(checkResults, 0, len(results))
+	for _, result := range results {
+		redacted = append(redacted, result.redacted())
+	}
+
+	return checkReport{
+		Tool: toolInfo{
+			Version:   version,
+			GitHash:   githash,
+			BuildDate: builddate,
+		},
+		Host:       collectHostInfo(),
+		Command:    config.cmd,
+		Arguments:  redactArguments(config.args),
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Summary: reportSummary{
+			Total: len(results),
+			Pass:  results.count(StatusPass),
+			Warn:  results.count(StatusWarn),
+			Fail:  results.count(StatusFail),
+			Skip:  results.count(StatusSkip),
+		},
+		Results: redacted,
+	}
+}
+
+func collectHostInfo() hostInfo {
+	info := hostInfo{
+		OS:   runtime.GOOS,
+		Arch: runtime.GOARCH,
+		CPUs: runtime.NumCPU(),
+	}
+
+	info.Hostname, _ = os.Hostname()
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return info
+	}
+
+	info.Interfaces = make(map[string][]string)
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		addresses, err := iface.Addrs()
+		if err != nil || len(addresses) == 0 {
+			continue
+		}
+
+		for _, address := range addresses {
+			info.Interfaces[iface.Name] = append(info.Interfaces[iface.Name], address.String())
+		}
+	}
+
+	return info
+}
+
+func (report checkReport) writeJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
+
+// writeReport emits the report in the configured output format to the configured destination.
+func writeReport(config runtimeConfiguration, report checkReport) error {
+	out := io.Writer(os.Stdout)
+	if config.outputFile != "" {
+		file, err := os.Create(config.outputFile)
+		if err != nil {
+			return fmt.Errorf("could not create report file %s - %s", config.outputFile, err.Error())
+		}
+		defer file.Close()
+		out = file
+	}
+
+	switch config.output {
+	case "json":
+		return report.writeJSON(out)
+	case "junit":
+		return report.writeJUnit(out)
+	default:
+		return fmt.Errorf("unsupported output format %s", config.output)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"net/netip"
 )
 
@@ -19,6 +20,7 @@ func runGlobalService(ctx context.Context, endCh chan<- string, app *application
 		listenIP, err = netip.ParseAddr(strListenIP)
 		if err != nil {
 			slog.Error(fmt.Sprintf("Failed to parse listen-ip argument (%s): %s", strListenIP, err.Error()))
+			app.setExitCode(exitUsage)
 			endCh <- "Global Controller mock service failed"
 			return
 		}
@@ -26,7 +28,7 @@ func runGlobalService(ctx context.Context, endCh chan<- string, app *application
 
 	// web: TCP port 80
 	app.wg.Add(1)
-	go app.startHTTPServer(ctx, listenIP, 80)
+	go app.startHTTPServer(ctx, listenIP, 80, http.HandlerFunc(app.httpRequestHandler))
 
 	// websecure: TCP port 443
 	// This service accepts WebSocket connections on /tunnel-ctrl
@@ -35,7 +37,7 @@ func runGlobalService(ctx context.Context, endCh chan<- string, app *application
 
 	// // tunnel HTTP proxy: TCP port 9010
 	// app.wg.Add(1)
-	// go app.startHTTPSServer(ctx, listenIP, 9010)
+	// go app.startHTTPSServer(ctx, listenIP, 9010, http.HandlerFunc(app.httpsRequestHandler))
 
 	// tunnel TCP proxy: TCP port 9091
 	app.wg.Add(1)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"net/netip"
 )
 
@@ -19,12 +20,34 @@ func runSiteService(ctx context.Context, endCh chan<- string, app *application,
 		listenIP, err = netip.ParseAddr(strListenIP)
 		if err != nil {
 			slog.Error(fmt.Sprintf("Failed to parse listen-ip argument (%s): %s", strListenIP, err.Error()))
+			app.setExitCode(exitUsage)
 			endCh <- "Site Controller mock service failed"
 			return
 		}
 	}
 
-	// DNS: UDP port 53
+	handler := dhcpHandler
+	if args["dhcp-pool"] != "" {
+		responder, err := newDHCPResponder(listenIP, args)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to configure DHCP responder - %s", err.Error()))
+			app.setExitCode(exitUsage)
+			endCh <- "Site Controller mock service failed"
+			return
+		}
+		slog.Info(fmt.Sprintf("DHCP responder offers %s-%s with next-server %s and bootfile %s", responder.poolStart, responder.poolEnd, responder.nextServer, responder.bootFile))
+		handler = responder.handle
+	}
+
+	// DHCP: UDP port 67
+	app.wg.Add(1)
+	go app.startDHCPServer(ctx, listenIP, 67, handler)
+
+	// TFTP: UDP port 69
+	app.wg.Add(1)
+	go app.startTFTPServer(ctx, listenIP, 69)
+
+	// HTTP boot: TCP port 80
 	app.wg.Add(1)
-	go app.startDHCPServer(ctx, listenIP, 53)
+	go app.startHTTPServer(ctx, listenIP, 80, http.HandlerFunc(newBootServer(app).requestHandler))
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+type checkFunc func(ctx context.Context) CheckResult
+
+// runChecks executes independent checks with at most app.parallel checks running at the same time.
+// The results are returned in the order of the checks regardless of the order in which they complete.
+func (app *application) runChecks(ctx context.Context, checks []checkFunc) checkResults {
+	results := make(checkResults, len(checks))
+
+	workers := max(1, min(app.parallel, len(checks)))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = checks[index](ctx)
+			}
+		}()
+	}
+
+	for index := range checks {
+		indexes <- index
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"metalsoft.io/prerequisite-check/certs"
+)
+
+// sshServerConfig is the account accepted by a mock SSH server, the prompt of its command line and its subsystems.
+type sshServerConfig struct {
+	username   string
+	password   string
+	prompt     string
+	subsystems map[string]func(channel ssh.Channel)
+}
+
+func (app *application) startSSHServer(ctx context.Context, ip netip.Addr, port uint16, config sshServerConfig) {
+	defer app.wg.Done()
+
+	address := netip.AddrPortFrom(ip, port).String()
+
+	serverConfig, err := config.serverConfig()
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error starting SSH server on %s - %s", address, err.Error()))
+		return
+	}
+
+	slog.Info(fmt.Sprintf("Starting SSH server on %s", address))
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error starting SSH server on %s - %s", address, err.Error()))
+		return
+	}
+	defer ln.Close()
+
+	go func() {
+		<-ctx.Done()
+
+		slog.Info(fmt.Sprintf("Shutting down SSH server on %s", address))
+
+		if err := ln.Close(); err != nil {
+			slog.Error(fmt.Sprintf("Error shutting down SSH server on %s - %s", address, err.Error()))
+		}
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				slog.Info(fmt.Sprintf("SSH server on %s shut down", address))
+				return
+			}
+			slog.Error(fmt.Sprintf("Could not accept SSH connection on %s - %s", address, err.Error()))
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		go config.connectionHandler(ctx, conn, serverConfig)
+	}
+}
+
+// serverConfig accepts the password and keyboard-interactive authentication methods, with the embedded key as host key.
+func (config sshServerConfig) serverConfig() (*ssh.ServerConfig, error) {
+	keyPEMBlock, err := certs.GetCert("key.pem")
+	if err != nil {
+		return nil, fmt.Errorf("could not load host key - %s", err.Error())
+	}
+	hostKey, err := ssh.ParsePrivateKey(keyPEMBlock)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse host key - %s", err.Error())
+	}
+
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return nil, config.authenticate(conn.User(), string(password))
+		},
+		KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := client(conn.User(), "", []string{"Password: "}, []bool{false})
+			if err != nil {
+				return nil, err
+			}
+			if len(answers) != 1 {
+				return nil, errors.New("unexpected number of answers")
+			}
+			return nil, config.authenticate(conn.User(), answers[0])
+		},
+		ServerVersion: "SSH-2.0-OpenSSH_8.0",
+	}
+	serverConfig.AddHostKey(hostKey)
+
+	return serverConfig, nil
+}
+
+func (config sshServerConfig) authenticate(username string, password string) error {
+	if subtle.ConstantTimeCompare([]byte(username), []byte(config.username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(config.password)) != 1 {
+		slog.Debug(fmt.Sprintf("SSH authentication failed for user %s", username))
+		return errors.New("invalid username or password")
+	}
+
+	return nil
+}
+
+func (config sshServerConfig) connectionHandler(ctx context.Context, socket net.Conn, serverConfig *ssh.ServerConfig) {
+	defer socket.Close()
+	slog.Debug(fmt.Sprintf("Processing SSH connection from %s", socket.RemoteAddr()))
+
+	socket.SetDeadline(time.Now().Add(30 * time.Second))
+
+	conn, channels, requests, err := ssh.NewServerConn(socket, serverConfig)
+	if err != nil {
+		slog.Debug(fmt.Sprintf("SSH handshake with %s failed - %s", socket.RemoteAddr(), err.Error()))
+		return
+	}
+	defer conn.Close()
+
+	// Interactive sessions are not limited once authenticated
+	socket.SetDeadline(time.Time{})
+
+	slog.Debug(fmt.Sprintf("SSH user %s logged in from %s", conn.User(), conn.RemoteAddr()))
+
+	go ssh.DiscardRequests(requests)
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			slog.Debug(fmt.Sprintf("Could not accept SSH channel from %s - %s", conn.RemoteAddr(), err.Error()))
+			continue
+		}
+		go config.sessionHandler(channel, requests)
+	}
+
+	slog.Debug(fmt.Sprintf("SSH connection from %s closed", conn.RemoteAddr()))
+}
+
+func (config sshServerConfig) sessionHandler(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for request := range requests {
+		switch request.Type {
+		case "pty-req", "env", "window-change":
+			request.Reply(true, nil)
+
+		case "shell":
+			request.Reply(true, nil)
+			go ssh.DiscardRequests(requests)
+			config.shell(channel)
+			return
+
+		case "subsystem":
+			var subsystem struct{ Name string }
+			ssh.Unmarshal(request.Payload, &subsystem)
+			handler, ok := config.subsystems[subsystem.Name]
+			if !ok {
+				slog.Debug(fmt.Sprintf("Unsupported SSH subsystem %s requested", subsystem.Name))
+				request.Reply(false, nil)
+				continue
+			}
+			request.Reply(true, nil)
+			go ssh.DiscardRequests(requests)
+			handler(channel)
+			return
+
+		case "exec":
+			request.Reply(true, nil)
+			var command struct{ Command string }
+			ssh.Unmarshal(request.Payload, &command)
+			fmt.Fprintf(channel, "%s\r\n", config.execute(command.Command))
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			return
+
+		default:
+			request.Reply(false, nil)
+		}
+	}
+}
+
+// shell runs a minimal command line, closed by exit or quit.
+func (config sshServerConfig) shell(channel ssh.Channel) {
+	reader := bufio.NewReader(channel)
+
+	for {
+		fmt.Fprint(channel, config.prompt)
+
+		line, err := reader.ReadString('\r')
+		if err != nil {
+			return
+		}
+
+		command := strings.TrimSpace(line)
+		switch command {
+		case "":
+			continue
+		case "exit", "quit":
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			return
+		default:
+			fmt.Fprintf(channel, "\r\n%s\r\n", config.execute(command))
+		}
+	}
+}
+
+func (config sshServerConfig) execute(command string) string {
+	slog.Debug(fmt.Sprintf("SSH command received: %s", command))
+
+	return fmt.Sprintf("ERROR: %s is not supported by the mock server", command)
+}
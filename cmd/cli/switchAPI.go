@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	OS10_SOFTWARE_URI  = "/restconf/data/dell-system-software:system-sw-state/sw-version"
+	SONIC_SOFTWARE_URI = "/restconf/data/openconfig-platform:components/component=SoftwareModule"
+	// The user roles of OS10 and SONiC are read from the OpenConfig AAA model
+	OPENCONFIG_USER_URI = "/restconf/data/openconfig-system:system/aaa/authentication/users/user="
+	NXAPI_URI           = "/ins"
+)
+
+// switchAPIInfo is what the management API of a switch reports about the OS and the user.
+type switchAPIInfo struct {
+	version string
+	model   string
+	roles   []string
+}
+
+// switchAPI describes the management API of a NOS and the user roles allowed to change the configuration.
+type switchAPI struct {
+	protocol   string
+	read       func(app *application, ctx context.Context, host string, port int, username string, password string) (switchAPIInfo, error)
+	writeRoles []string
+}
+
+// JunOS is managed through NETCONF, see testNETCONFConnection
+var switchAPIs = map[string]switchAPI{
+	"os10": {
+		protocol:   "RESTCONF",
+		read:       (*application).readOS10API,
+		writeRoles: []string{"sysadmin", "netadmin"},
+	},
+	"sonic": {
+		protocol:   "RESTCONF",
+		read:       (*application).readSONiCAPI,
+		writeRoles: []string{"admin"},
+	},
+	"cisco": {
+		protocol:   "NX-API",
+		read:       (*application).readNXAPI,
+		writeRoles: []string{"network-admin", "vdc-admin"},
+	},
+}
+
+// testSwitchAPI reads the OS version through the management API of the NOS and verifies that the user can change the configuration.
+func (app *application) testSwitchAPI(ctx context.Context, host string, port int, username string, password string, nos string) CheckResult {
+	api, ok := switchAPIs[strings.ToLower(nos)]
+	if !ok {
+		result := newCheckResult("SwitchAPI", host, port)
+		return result.fail(fmt.Errorf("no management API probe for NOS '%s'", nos))
+	}
+
+	slog.Debug(fmt.Sprintf("Testing %s API of %s switch %s:%d", api.protocol, nos, host, port))
+
+	// The check keeps the same protocol for every NOS, so that a switch has the same report columns and identifiers
+	result := newCheckResult("SwitchAPI", host, port)
+	result.addFact("API", api.protocol)
+
+	info, err := api.read(app, ctx, host, port, username, password)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to read %s API of switch %s:%d - %s", api.protocol, host, port, err.Error()))
+		return result.fail(err)
+	}
+
+	// The roles are often reported in both the config and the state of the user
+	slices.Sort(info.roles)
+	info.roles = slices.Compact(info.roles)
+
+	result.addFact("Version", info.version)
+	result.addFact("Model", info.model)
+	result.addFact("Roles", strings.Join(info.roles, ","))
+
+	slog.Debug(fmt.Sprintf("Switch %s:%d runs %s %s - user %s has roles %s", host, port, nos, info.version, username, strings.Join(info.roles, ",")))
+
+	if info.version == "" {
+		slog.Warn(fmt.Sprintf("%s API of switch %s:%d did not report the OS version", api.protocol, host, port))
+		result = result.warn(errors.New("the OS version is not reported"))
+		result.ErrorClass = "capability"
+		return result
+	}
+
+	if len(info.roles) == 0 {
+		slog.Warn(fmt.Sprintf("Could not read the roles of user %s on switch %s:%d", username, host, port))
+		result = result.warn(fmt.Errorf("could not verify that user %s has write privilege", username))
+		result.ErrorClass = "capability"
+		return result
+	}
+
+	if !slices.ContainsFunc(info.roles, func(role string) bool {
+		return slices.Contains(api.writeRoles, strings.ToLower(role))
+	}) {
+		slog.Error(fmt.Sprintf("User %s on switch %s:%d has roles %s - one of %s is required", username, host, port, strings.Join(info.roles, ","), strings.Join(api.writeRoles, ",")))
+		result = result.fail(fmt.Errorf("user %s has roles %s without write privilege - one of %s is required", username, strings.Join(info.roles, ","), strings.Join(api.writeRoles, ",")))
+		result.ErrorClass = "forbidden"
+		return result
+	}
+
+	return result.pass()
+}
+
+func (app *application) readOS10API(ctx context.Context, host string, port int, username string, password string) (switchAPIInfo, error) {
+	info := switchAPIInfo{}
+
+	data, err := app.switchAPIRequest(ctx, http.MethodGet, host, port, username, password, OS10_SOFTWARE_URI, nil)
+	if err != nil {
+		return info, err
+	}
+	info.version = firstString(findStrings(data, "sw-version"))
+	info.model = firstString(findStrings(data, "sw-platform"))
+
+	info.roles = app.readOpenConfigRoles(ctx, host, port, username, password)
+
+	return info, nil
+}
+
+func (app *application) readSONiCAPI(ctx context.Context, host string, port int, username string, password string) (switchAPIInfo, error) {
+	info := switchAPIInfo{}
+
+	data, err := app.switchAPIRequest(ctx, http.MethodGet, host, port, username, password, SONIC_SOFTWARE_URI, nil)
+	if err != nil {
+		return info, err
+	}
+	info.version = firstString(findStrings(data, "software-version"))
+	info.model = firstString(findStrings(data, "hwsku-version"))
+
+	info.roles = app.readOpenConfigRoles(ctx, host, port, username, password)
+
+	return info, nil
+}
+
+// readOpenConfigRoles returns the roles of the user, or nothing if the API does not expose them.
+func (app *application) readOpenConfigRoles(ctx context.Context, host string, port int, username string, password string) []string {
+	data, err := app.switchAPIRequest(ctx, http.MethodGet, host, port, username, password, OPENCONFIG_USER_URI+url.PathEscape(username), nil)
+	if err != nil {
+		slog.Debug(fmt.Sprintf("Could not read the roles of user %s on switch %s:%d - %s", username, host, port, err.Error()))
+		return nil
+	}
+
+	return findStrings(data, "role")
+}
+
+func (app *application) readNXAPI(ctx context.Context, host string, port int, username string, password string) (switchAPIInfo, error) {
+	info := switchAPIInfo{}
+
+	body, err := app.nxapiShow(ctx, host, port, username, password, "show version")
+	if err != nil {
+		return info, err
+	}
+	info.version = firstString(findStrings(body, "nxos_ver_str"))
+	if info.version == "" {
+		info.version = firstString(findStrings(body, "sys_ver_str"))
+	}
+	info.model = firstString(findStrings(body, "chassis_id"))
+
+	body, err = app.nxapiShow(ctx, host, port, username, password, "show user-account "+username)
+	if err != nil {
+		slog.Debug(fmt.Sprintf("Could not read the roles of user %s on switch %s:%d - %s", username, host, port, err.Error()))
+		return info, nil
+	}
+	info.roles = findStrings(body, "role")
+
+	return info, nil
+}
+
+// nxapiShow runs a show command through NX-API and returns the body of its JSON output.
+func (app *application) nxapiShow(ctx context.Context, host string, port int, username string, password string, command string) (interface{}, error) {
+	request := map[string]interface{}{
+		"ins_api": map[string]string{
+			"version":       "1.0",
+			"type":          "cli_show",
+			"chunk":         "0",
+			"sid":           "1",
+			"input":         command,
+			"output_format": "json",
+		},
+	}
+
+	data, err := app.switchAPIRequest(ctx, http.MethodPost, host, port, username, password, NXAPI_URI, request)
+	if err != nil {
+		return nil, err
+	}
+
+	output := safeMap(safeMap(safeMap(data, "ins_api"), "outputs"), "output")
+	if code := fmt.Sprint(output["code"]); code != strconv.Itoa(http.StatusOK) {
+		return nil, fmt.Errorf("%s failed with code %s - %s", command, code, fmt.Sprint(output["msg"]))
+	}
+
+	return output["body"], nil
+}
+
+// switchAPIRequest sends a request with basic authentication to the management API and parses the JSON response.
+func (app *application) switchAPIRequest(ctx context.Context, method string, host string, port int, username string, password string, uri string, body interface{}) (interface{}, error) {
+	link := "https://" + host + ":" + strconv.Itoa(port) + uri
+
+	request := resty.New().
+		SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true}).
+		SetTimeout(app.timeouts(ctx).total()).
+		R().
+		SetContext(ctx).
+		SetBasicAuth(username, password).
+		SetHeader("Accept", "application/yang-data+json, application/json")
+	if body != nil {
+		request.SetHeader("Content-Type", "application/json").SetBody(body)
+	}
+
+	response, err := request.Execute(method, link)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug(fmt.Sprintf("Switch API %s %s returned %s", method, link, response.Status()))
+
+	if response.StatusCode() != http.StatusOK {
+		return nil, &statusError{code: response.StatusCode(), status: response.Status()}
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(response.Body(), &data); err != nil {
+		slog.Debug(fmt.Sprintf("Could not parse JSON response - %s", err.Error()))
+		return nil, fmt.Errorf("could not parse response")
+	}
+
+	return data, nil
+}
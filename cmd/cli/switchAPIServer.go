@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// mockSwitchSoftware is the OS version and model reported by the management API of each NOS.
+var mockSwitchSoftware = map[string]struct{ version, model string }{
+	"os10":  {"10.5.4.3", "S5248F-ON"},
+	"sonic": {"SONiC-OS-4.1.1-Enterprise_Base", "Accton-AS7326-56X"},
+	"cisco": {"10.2(5)", "Nexus9000 C93180YC-FX Chassis"},
+}
+
+// mockSwitchAPI emulates the management API of a NOS - RESTCONF for OS10 and SONiC, NX-API for Cisco.
+// The user has the first role with write privilege of the NOS.
+type mockSwitchAPI struct {
+	app      *application
+	nos      string
+	username string
+	password string
+}
+
+func (api *mockSwitchAPI) requestHandler(w http.ResponseWriter, r *http.Request) {
+	software, ok := mockSwitchSoftware[api.nos]
+	if !ok || (!strings.HasPrefix(r.URL.Path, "/restconf/") && r.URL.Path != NXAPI_URI) {
+		api.app.httpsRequestHandler(w, r)
+		return
+	}
+
+	slog.Debug(fmt.Sprintf("Switch API request received from %s: %s %s", r.RemoteAddr, r.Method, r.URL.Path))
+
+	username, password, ok := r.BasicAuth()
+	if !ok || subtle.ConstantTimeCompare([]byte(username), []byte(api.username)) != 1 || subtle.ConstantTimeCompare([]byte(password), []byte(api.password)) != 1 {
+		slog.Debug(fmt.Sprintf("Switch API login of %s from %s rejected", username, r.RemoteAddr))
+		w.Header().Set("WWW-Authenticate", `Basic realm="switch"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role := switchAPIs[api.nos].writeRoles[0]
+
+	switch {
+	case api.nos == "os10" && r.Method == http.MethodGet && r.URL.Path == OS10_SOFTWARE_URI:
+		writeMockJSON(w, map[string]interface{}{
+			"dell-system-software:sw-version": map[string]string{
+				"sw-version":  software.version,
+				"sw-platform": software.model,
+			},
+		})
+
+	case api.nos == "sonic" && r.Method == http.MethodGet && r.URL.Path == SONIC_SOFTWARE_URI:
+		writeMockJSON(w, map[string]interface{}{
+			"openconfig-platform:component": []interface{}{
+				map[string]interface{}{
+					"name": "SoftwareModule",
+					"openconfig-platform-ext:software": map[string]string{
+						"software-version": software.version,
+						"hwsku-version":    software.model,
+					},
+				},
+			},
+		})
+
+	case (api.nos == "os10" || api.nos == "sonic") && r.Method == http.MethodGet && r.URL.Path == OPENCONFIG_USER_URI+api.username:
+		writeMockJSON(w, map[string]interface{}{
+			"openconfig-system:user": []interface{}{
+				map[string]interface{}{
+					"username": api.username,
+					"config":   map[string]string{"username": api.username, "role": role},
+					"state":    map[string]string{"username": api.username, "role": role},
+				},
+			},
+		})
+
+	case api.nos == "cisco" && r.Method == http.MethodPost && r.URL.Path == NXAPI_URI:
+		api.nxapiHandler(w, r, software.version, software.model, role)
+
+	default:
+		http.Error(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+func (api *mockSwitchAPI) nxapiHandler(w http.ResponseWriter, r *http.Request, version string, model string, role string) {
+	var request struct {
+		InsAPI struct {
+			Input string `json:"input"`
+		} `json:"ins_api"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	output := map[string]interface{}{
+		"input": request.InsAPI.Input,
+		"code":  "200",
+		"msg":   "Success",
+	}
+
+	switch request.InsAPI.Input {
+	case "show version":
+		output["body"] = map[string]string{
+			"nxos_ver_str": version,
+			"chassis_id":   model,
+			"host_name":    MOCK_SWITCH_HOSTNAME,
+		}
+	case "show user-account " + api.username:
+		output["body"] = map[string]interface{}{
+			"TABLE_template": map[string]interface{}{
+				"ROW_template": map[string]interface{}{
+					"usr_name":   api.username,
+					"TABLE_role": map[string]interface{}{"ROW_role": map[string]string{"role": role}},
+				},
+			},
+		}
+	default:
+		output["code"] = "400"
+		output["msg"] = "Input CLI command error"
+	}
+
+	writeMockJSON(w, map[string]interface{}{
+		"ins_api": map[string]interface{}{
+			"type":    "cli_show",
+			"version": "1.0",
+			"sid":     "eoc",
+			"outputs": map[string]interface{}{"output": output},
+		},
+	})
+}
+
+func writeMockJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error(fmt.Sprintf("Error writing switch API response - %s", err.Error()))
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// The mock switch answers the JunOS get-software-information RPC as a QFX5120
+const (
+	MOCK_SWITCH_HOSTNAME = "mock-switch"
+	MOCK_SWITCH_MODEL    = "qfx5120-48y-8c"
+	MOCK_SWITCH_VERSION  = "22.4R3-S2.11"
+)
+
+func runSwitchService(ctx context.Context, endCh chan<- string, app *application, args map[string]string) {
+	slog.Info("Starting switch mock service", "arguments", args)
+
+	var listenIP netip.Addr
+	strListenIP, ok := args["listen-ip"]
+	if !ok {
+		listenIP = netip.IPv4Unspecified()
+	} else {
+		var err error
+		listenIP, err = netip.ParseAddr(strListenIP)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to parse listen-ip argument (%s): %s", strListenIP, err.Error()))
+			app.setExitCode(exitUsage)
+			endCh <- "Switch mock service failed"
+			return
+		}
+	}
+
+	nos := strings.ToLower(args["nos"])
+	if _, ok := mockSwitchSoftware[nos]; !ok && nos != "junos" {
+		slog.Error(fmt.Sprintf("Unsupported nos argument (%s) - expected one of (OS10, SONiC, JunOS, Cisco)", args["nos"]))
+		app.setExitCode(exitUsage)
+		endCh <- "Switch mock service failed"
+		return
+	}
+
+	ports := map[string]uint16{}
+	for _, key := range []string{"http-port", "https-port", "ssh-port", "netconf-port"} {
+		port, err := strconv.ParseUint(args[key], 10, 16)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to parse %s argument (%s): %s", key, args[key], err.Error()))
+			app.setExitCode(exitUsage)
+			endCh <- "Switch mock service failed"
+			return
+		}
+		ports[key] = uint16(port)
+	}
+
+	// HTTP: TCP port 80
+	app.wg.Add(1)
+	go app.startHTTPServer(ctx, listenIP, ports["http-port"], http.HandlerFunc(app.httpRequestHandler))
+
+	// HTTPS and the management API of the NOS: TCP port 443
+	api := &mockSwitchAPI{app: app, nos: nos, username: args["username"], password: args["password"]}
+	app.wg.Add(1)
+	go app.startHTTPSServer(ctx, listenIP, ports["https-port"], http.HandlerFunc(api.requestHandler))
+
+	// SSH: TCP port 22
+	app.wg.Add(1)
+	go app.startSSHServer(ctx, listenIP, ports["ssh-port"], sshServerConfig{
+		username: args["username"],
+		password: args["password"],
+		prompt:   MOCK_SWITCH_HOSTNAME + "# ",
+	})
+
+	// NETCONF over SSH: TCP port 830
+	app.wg.Add(1)
+	go app.startSSHServer(ctx, listenIP, ports["netconf-port"], sshServerConfig{
+		username: args["username"],
+		password: args["password"],
+		subsystems: map[string]func(channel ssh.Channel){
+			"netconf": netconfSubsystem(MOCK_SWITCH_HOSTNAME),
+		},
+	})
+}
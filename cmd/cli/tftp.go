@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	TFTP_OPCODE_RRQ   = 1
+	TFTP_OPCODE_WRQ   = 2
+	TFTP_OPCODE_DATA  = 3
+	TFTP_OPCODE_ACK   = 4
+	TFTP_OPCODE_ERROR = 5
+	TFTP_OPCODE_OACK  = 6
+
+	TFTP_ERROR_NOT_DEFINED       = 0
+	TFTP_ERROR_FILE_NOT_FOUND    = 1
+	TFTP_ERROR_ACCESS_VIOLATION  = 2
+	TFTP_ERROR_ILLEGAL_OPERATION = 4
+	TFTP_ERROR_UNKNOWN_TID       = 5
+
+	// RFC 2348 block sizes
+	TFTP_DEFAULT_BLOCK_SIZE = 512
+	TFTP_MIN_BLOCK_SIZE     = 8
+	TFTP_MAX_BLOCK_SIZE     = 65464
+
+	// The largest block that fits an Ethernet frame, requested by the TFTP probe
+	TFTP_PROBE_BLOCK_SIZE = 1468
+
+	TFTP_TIMEOUT = time.Second
+	TFTP_RETRIES = 5
+
+	// The only file served by the TFTP server of the site-service command
+	TFTP_TEST_FILE = "metalsoft-test.ipxe"
+)
+
+//go:embed boot/metalsoft-test.ipxe
+var tftpTestPayload []byte
+
+// tftpRequest is a read or write request with the RFC 2347 options.
+type tftpRequest struct {
+	opcode   uint16
+	filename string
+	mode     string
+	options  map[string]string
+}
+
+func parseTFTPRequest(packet []byte) (tftpRequest, error) {
+	request := tftpRequest{options: map[string]string{}}
+
+	if len(packet) < 4 {
+		return request, errors.New("packet too short")
+	}
+	request.opcode = binary.BigEndian.Uint16(packet)
+
+	fields := strings.Split(string(packet[2:]), "\x00")
+	// The packet ends with a null byte, which leaves an empty last field
+	if len(fields) < 3 || fields[len(fields)-1] != "" {
+		return request, errors.New("malformed request")
+	}
+	fields = fields[:len(fields)-1]
+
+	request.filename = fields[0]
+	request.mode = strings.ToLower(fields[1])
+	for i := 2; i+1 < len(fields); i += 2 {
+		request.options[strings.ToLower(fields[i])] = fields[i+1]
+	}
+
+	return request, nil
+}
+
+func (r tftpRequest) marshal() []byte {
+	packet := binary.BigEndian.AppendUint16(nil, r.opcode)
+	fields := []string{r.filename, r.mode}
+	for _, name := range slices.Sorted(maps.Keys(r.options)) {
+		fields = append(fields, name, r.options[name])
+	}
+	for _, field := range fields {
+		packet = append(packet, field...)
+		packet = append(packet, 0)
+	}
+	return packet
+}
+
+// parseTFTPOptions reads the options acknowledged by an OACK packet.
+func parseTFTPOptions(packet []byte) map[string]string {
+	options := map[string]string{}
+
+	fields := strings.Split(string(packet[2:]), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		options[strings.ToLower(fields[i])] = fields[i+1]
+	}
+
+	return options
+}
+
+func tftpDataPacket(block uint16, data []byte) []byte {
+	packet := binary.BigEndian.AppendUint16(nil, TFTP_OPCODE_DATA)
+	packet = binary.BigEndian.AppendUint16(packet, block)
+	return append(packet, data...)
+}
+
+func tftpAckPacket(block uint16) []byte {
+	packet := binary.BigEndian.AppendUint16(nil, TFTP_OPCODE_ACK)
+	return binary.BigEndian.AppendUint16(packet, block)
+}
+
+func tftpErrorPacket(code uint16, message string) []byte {
+	packet := binary.BigEndian.AppendUint16(nil, TFTP_OPCODE_ERROR)
+	packet = binary.BigEndian.AppendUint16(packet, code)
+	packet = append(packet, message...)
+	return append(packet, 0)
+}
+
+func tftpOACKPacket(options map[string]string) []byte {
+	packet := binary.BigEndian.AppendUint16(nil, TFTP_OPCODE_OACK)
+	for _, name := range slices.Sorted(maps.Keys(options)) {
+		packet = append(packet, name...)
+		packet = append(packet, 0)
+		packet = append(packet, options[name]...)
+		packet = append(packet, 0)
+	}
+	return packet
+}
+
+// parseTFTPError returns the error carried by an ERROR packet.
+func parseTFTPError(packet []byte) error {
+	if len(packet) < 4 {
+		return errors.New("TFTP error")
+	}
+	message, _, _ := bytes.Cut(packet[4:], []byte{0})
+	return fmt.Errorf("TFTP error %d - %s", binary.BigEndian.Uint16(packet[2:]), string(message))
+}
+
+// tftpNetascii converts the line endings of the payload for a netascii transfer.
+func tftpNetascii(payload []byte) []byte {
+	payload = bytes.ReplaceAll(payload, []byte("\r"), []byte("\r\x00"))
+	return bytes.ReplaceAll(payload, []byte("\n"), []byte("\r\n"))
+}
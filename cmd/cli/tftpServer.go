@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// startTFTPServer serves the embedded test boot file. Each transfer runs from its own ephemeral port as
+// required by the protocol, which is what firewalls between the servers and the site controller often block.
+func (app *application) startTFTPServer(ctx context.Context, ip netip.Addr, port uint16) {
+	defer app.wg.Done()
+
+	address := netip.AddrPortFrom(ip, port).String()
+
+	slog.Info(fmt.Sprintf("Starting TFTP server on %s", address))
+
+	ln, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(netip.AddrPortFrom(ip, port)))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error starting TFTP server on %s - %s", address, err.Error()))
+		return
+	}
+	defer ln.Close()
+
+	go func() {
+		<-ctx.Done()
+
+		slog.Info(fmt.Sprintf("Shutting down TFTP server on %s", address))
+
+		if err := ln.Close(); err != nil {
+			slog.Error(fmt.Sprintf("Error shutting down TFTP server on %s - %s", address, err.Error()))
+		}
+	}()
+
+	buffer := make([]byte, 1500)
+
+	for {
+		bytesRead, peer, err := ln.ReadFromUDP(buffer)
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				slog.Info(fmt.Sprintf("TFTP server on %s shut down", address))
+				return
+			}
+			slog.Error(fmt.Sprintf("Could not read TFTP packet on %s - %s", address, err.Error()))
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		request, err := parseTFTPRequest(buffer[:bytesRead])
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Invalid TFTP packet from %s - %s", peer, err.Error()))
+			continue
+		}
+
+		app.wg.Add(1)
+		go app.tftpTransfer(ctx, ip, peer, request)
+	}
+}
+
+// tftpTransfer answers a request from a new ephemeral port.
+func (app *application) tftpTransfer(ctx context.Context, ip netip.Addr, peer *net.UDPAddr, request tftpRequest) {
+	defer app.wg.Done()
+
+	conn, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(netip.AddrPortFrom(ip, 0)))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Could not open TFTP transfer port for %s - %s", peer, err.Error()))
+		return
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	switch request.opcode {
+	case TFTP_OPCODE_RRQ:
+	case TFTP_OPCODE_WRQ:
+		slog.Warn(fmt.Sprintf("TFTP write request from %s for %s rejected", peer, request.filename))
+		conn.WriteToUDP(tftpErrorPacket(TFTP_ERROR_ACCESS_VIOLATION, "the server is read only"), peer)
+		return
+	default:
+		slog.Warn(fmt.Sprintf("Unexpected TFTP packet with opcode %d from %s", request.opcode, peer))
+		conn.WriteToUDP(tftpErrorPacket(TFTP_ERROR_ILLEGAL_OPERATION, "illegal TFTP operation"), peer)
+		return
+	}
+
+	slog.Info(fmt.Sprintf("TFTP read request from %s for %s in %s mode with options %v", peer, request.filename, request.mode, request.options))
+
+	if strings.TrimPrefix(request.filename, "/") != TFTP_TEST_FILE {
+		slog.Warn(fmt.Sprintf("TFTP file %s requested by %s not found - only %s is served", request.filename, peer, TFTP_TEST_FILE))
+		conn.WriteToUDP(tftpErrorPacket(TFTP_ERROR_FILE_NOT_FOUND, "file not found"), peer)
+		return
+	}
+
+	payload := tftpTestPayload
+	switch request.mode {
+	case "octet":
+	case "netascii":
+		payload = tftpNetascii(payload)
+	default:
+		slog.Warn(fmt.Sprintf("TFTP mode %s requested by %s is not supported", request.mode, peer))
+		conn.WriteToUDP(tftpErrorPacket(TFTP_ERROR_ILLEGAL_OPERATION, "unsupported mode"), peer)
+		return
+	}
+
+	// Options the server does not know are left out of the acknowledgement as RFC 2347 requires
+	blockSize := TFTP_DEFAULT_BLOCK_SIZE
+	accepted := map[string]string{}
+	if value, ok := request.options["blksize"]; ok {
+		if size, err := strconv.Atoi(value); err == nil && size >= TFTP_MIN_BLOCK_SIZE {
+			blockSize = min(size, TFTP_MAX_BLOCK_SIZE)
+			accepted["blksize"] = strconv.Itoa(blockSize)
+		}
+	}
+	if _, ok := request.options["tsize"]; ok {
+		accepted["tsize"] = strconv.Itoa(len(payload))
+	}
+
+	start := time.Now()
+
+	if len(accepted) > 0 {
+		if err := tftpSend(conn, peer, tftpOACKPacket(accepted), 0); err != nil {
+			slog.Error(fmt.Sprintf("TFTP transfer of %s to %s failed waiting for the options acknowledgement - %s", request.filename, peer, err.Error()))
+			return
+		}
+	}
+
+	// The last block is shorter than the block size, which takes an empty block when the size is a multiple of it
+	blocks := len(payload)/blockSize + 1
+	for i := range blocks {
+		data := payload[i*blockSize : min((i+1)*blockSize, len(payload))]
+		block := uint16(i + 1)
+
+		if err := tftpSend(conn, peer, tftpDataPacket(block, data), block); err != nil {
+			slog.Error(fmt.Sprintf("TFTP transfer of %s to %s failed after %d of %d blocks - %s", request.filename, peer, i, blocks, err.Error()))
+			return
+		}
+
+		slog.Debug(fmt.Sprintf("TFTP block %d of %d acknowledged by %s", block, blocks, peer))
+	}
+
+	slog.Info(fmt.Sprintf("TFTP transfer of %s to %s completed - %d bytes in %d blocks of %d bytes in %s", request.filename, peer, len(payload), blocks, blockSize, time.Since(start).Round(time.Millisecond)))
+}
+
+// tftpSend sends a packet and waits for the acknowledgement of the block, retransmitting it on timeout.
+func tftpSend(conn *net.UDPConn, peer *net.UDPAddr, packet []byte, block uint16) error {
+	buffer := make([]byte, 1500)
+
+	for attempt := 1; attempt <= TFTP_RETRIES; attempt++ {
+		if _, err := conn.WriteToUDP(packet, peer); err != nil {
+			return err
+		}
+
+		deadline := time.Now().Add(TFTP_TIMEOUT)
+		for {
+			if err := conn.SetReadDeadline(deadline); err != nil {
+				return err
+			}
+
+			bytesRead, from, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					slog.Debug(fmt.Sprintf("No TFTP acknowledgement of block %d from %s - attempt %d of %d", block, peer, attempt, TFTP_RETRIES))
+					break
+				}
+				return err
+			}
+
+			if !from.IP.Equal(peer.IP) || from.Port != peer.Port {
+				conn.WriteToUDP(tftpErrorPacket(TFTP_ERROR_UNKNOWN_TID, "unknown transfer ID"), from)
+				continue
+			}
+			if bytesRead < 4 {
+				continue
+			}
+
+			switch binary.BigEndian.Uint16(buffer) {
+			case TFTP_OPCODE_ERROR:
+				return parseTFTPError(buffer[:bytesRead])
+			case TFTP_OPCODE_ACK:
+				// Duplicate acknowledgements of earlier blocks are ignored
+				if binary.BigEndian.Uint16(buffer[2:]) == block {
+					return nil
+				}
+			}
+		}
+	}
+
+	return fmt.Errorf("no acknowledgement of block %d after %d attempts", block, TFTP_RETRIES)
+}
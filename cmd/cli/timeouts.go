@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	DEFAULT_CONNECT_TIMEOUT = 10 * time.Second
+	DEFAULT_READ_TIMEOUT    = 10 * time.Second
+	DEFAULT_BACKOFF         = time.Second
+)
+
+// checkTimeouts limits the time a probe waits for the connection and for the response.
+type checkTimeouts struct {
+	Connect time.Duration
+	Read    time.Duration
+}
+
+// retryPolicy describes how a failed check is repeated.
+type retryPolicy struct {
+	Retries int
+	// Delay before the first retry, doubled for every following retry
+	Backoff time.Duration
+}
+
+type checkAttempt struct {
+	Status     CheckStatus `json:"status"`
+	DurationMs int64       `json:"duration_ms"`
+	ErrorClass string      `json:"error_class,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Failures that are never repeated - a repeated failed login risks locking the account
+var permanentErrorClasses = []string{"auth", "forbidden", "account-locked", "invalid-check"}
+
+type timeoutsKey struct{}
+
+func withCheckTimeouts(ctx context.Context, timeouts checkTimeouts) context.Context {
+	return context.WithValue(ctx, timeoutsKey{}, timeouts)
+}
+
+// timeouts returns the timeouts of the check running with the context, or the global ones.
+func (app *application) timeouts(ctx context.Context) checkTimeouts {
+	if timeouts, ok := ctx.Value(timeoutsKey{}).(checkTimeouts); ok {
+		return timeouts
+	}
+
+	return app.defaultTimeouts
+}
+
+// total is the overall time allowed to probes that do not distinguish connecting and reading.
+func (t checkTimeouts) total() time.Duration {
+	return t.Connect + t.Read
+}
+
+func (t checkTimeouts) httpClient(insecure bool) *http.Client {
+	dialer := &net.Dialer{Timeout: t.Connect}
+
+	var transport *http.Transport
+	if insecure {
+		transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = t.Connect
+	transport.ResponseHeaderTimeout = t.Read
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   t.total(),
+	}
+}
+
+// retry runs the probe until it does not fail or the retries of the policy are exhausted.
+func (app *application) retry(ctx context.Context, policy retryPolicy, probe checkFunc) CheckResult {
+	started := time.Now()
+	attempts := []checkAttempt{}
+	backoff := policy.Backoff
+
+	for attempt := 0; ; attempt++ {
+		result := probe(ctx)
+
+		if policy.Retries == 0 {
+			return result
+		}
+
+		attempts = append(attempts, checkAttempt{
+			Status:     result.Status,
+			DurationMs: result.Duration.Milliseconds(),
+			ErrorClass: result.ErrorClass,
+			Error:      result.Error,
+		})
+
+		if result.Status != StatusFail || attempt >= policy.Retries || slices.Contains(permanentErrorClasses, result.ErrorClass) {
+			result.Attempts = attempts
+			result.Duration = time.Since(started)
+			return result
+		}
+
+		slog.Warn(fmt.Sprintf("Check %s failed - retrying in %s (%d/%d)", result.Name, backoff, attempt+1, policy.Retries))
+
+		select {
+		case <-ctx.Done():
+			result.Attempts = attempts
+			result.Duration = time.Since(started)
+			return result
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/bits"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+const (
+	VNC_PROTOCOL_VERSION  = "RFB 003.008\n"
+	VNC_SECURITY_VNC_AUTH = 2
+	VNC_SCREEN_WIDTH      = 1024
+	VNC_SCREEN_HEIGHT     = 768
+	VNC_DESKTOP_NAME      = "iDRAC Virtual Console"
+)
+
+func (app *application) startVNCServer(ctx context.Context, ip netip.Addr, port uint16, bmc *mockBMC) {
+	defer app.wg.Done()
+
+	address := netip.AddrPortFrom(ip, port).String()
+
+	slog.Info(fmt.Sprintf("Starting VNC server on %s", address))
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error starting VNC server on %s - %s", address, err.Error()))
+		return
+	}
+	defer ln.Close()
+
+	go func() {
+		<-ctx.Done()
+
+		slog.Info(fmt.Sprintf("Shutting down VNC server on %s", address))
+
+		if err := ln.Close(); err != nil {
+			slog.Error(fmt.Sprintf("Error shutting down VNC server on %s - %s", address, err.Error()))
+		}
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				slog.Info(fmt.Sprintf("VNC server on %s shut down", address))
+				return
+			}
+			slog.Error(fmt.Sprintf("Could not accept VNC connection on %s - %s", address, err.Error()))
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		go bmc.vncConnectionHandler(ctx, conn)
+	}
+}
+
+// vncConnectionHandler runs the RFB 3.8 handshake with VNC authentication, then ignores the client messages.
+func (bmc *mockBMC) vncConnectionHandler(ctx context.Context, socket net.Conn) {
+	defer socket.Close()
+	slog.Debug(fmt.Sprintf("Processing VNC connection from %s", socket.RemoteAddr()))
+
+	go func() {
+		<-ctx.Done()
+		socket.Close()
+	}()
+
+	socket.SetDeadline(time.Now().Add(30 * time.Second))
+
+	if err := bmc.vncHandshake(socket); err != nil {
+		slog.Debug(fmt.Sprintf("VNC handshake with %s failed - %s", socket.RemoteAddr(), err.Error()))
+		return
+	}
+
+	slog.Debug(fmt.Sprintf("VNC client %s connected", socket.RemoteAddr()))
+
+	socket.SetDeadline(time.Time{})
+	io.Copy(io.Discard, socket)
+
+	slog.Debug(fmt.Sprintf("VNC connection from %s closed", socket.RemoteAddr()))
+}
+
+func (bmc *mockBMC) vncHandshake(socket net.Conn) error {
+	if _, err := socket.Write([]byte(VNC_PROTOCOL_VERSION)); err != nil {
+		return err
+	}
+	version := make([]byte, len(VNC_PROTOCOL_VERSION))
+	if _, err := io.ReadFull(socket, version); err != nil {
+		return err
+	}
+	if string(version) != VNC_PROTOCOL_VERSION {
+		return fmt.Errorf("unsupported protocol version %q", strings.TrimSpace(string(version)))
+	}
+
+	if _, err := socket.Write([]byte{1, VNC_SECURITY_VNC_AUTH}); err != nil {
+		return err
+	}
+	securityType := make([]byte, 1)
+	if _, err := io.ReadFull(socket, securityType); err != nil {
+		return err
+	}
+	if securityType[0] != VNC_SECURITY_VNC_AUTH {
+		return fmt.Errorf("unsupported security type %d", securityType[0])
+	}
+
+	challenge := make([]byte, 16)
+	rand.Read(challenge)
+	if _, err := socket.Write(challenge); err != nil {
+		return err
+	}
+	response := make([]byte, 16)
+	if _, err := io.ReadFull(socket, response); err != nil {
+		return err
+	}
+
+	expected, err := vncEncryptChallenge(bmc.vncPassword, challenge)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(response, expected) != 1 {
+		reason := "Authentication failed"
+		failure := binary.BigEndian.AppendUint32([]byte{0, 0, 0, 1}, uint32(len(reason)))
+		socket.Write(append(failure, reason...))
+		return fmt.Errorf("invalid password")
+	}
+	if _, err := socket.Write([]byte{0, 0, 0, 0}); err != nil {
+		return err
+	}
+
+	// ClientInit - shared desktop flag
+	if _, err := io.ReadFull(socket, make([]byte, 1)); err != nil {
+		return err
+	}
+
+	// ServerInit - 32 bits per pixel, depth 24, little endian true color
+	serverInit := binary.BigEndian.AppendUint16(nil, VNC_SCREEN_WIDTH)
+	serverInit = binary.BigEndian.AppendUint16(serverInit, VNC_SCREEN_HEIGHT)
+	serverInit = append(serverInit, 32, 24, 0, 1, 0, 255, 0, 255, 0, 255, 16, 8, 0, 0, 0, 0)
+	serverInit = binary.BigEndian.AppendUint32(serverInit, uint32(len(VNC_DESKTOP_NAME)))
+	serverInit = append(serverInit, VNC_DESKTOP_NAME...)
+	_, err = socket.Write(serverInit)
+
+	return err
+}
+
+// vncEncryptChallenge encrypts the challenge with DES, keyed with the first 8 bytes of the password with mirrored bits.
+func vncEncryptChallenge(password string, challenge []byte) ([]byte, error) {
+	key := make([]byte, 8)
+	copy(key, password)
+	for index := range key {
+		key[index] = bits.Reverse8(key[index])
+	}
+
+	block, err := des.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := make([]byte, len(challenge))
+	for offset := 0; offset < len(challenge); offset += des.BlockSize {
+		block.Encrypt(encrypted[offset:offset+des.BlockSize], challenge[offset:offset+des.BlockSize])
+	}
+
+	return encrypted, nil
+}
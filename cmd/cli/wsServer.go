@@ -26,17 +26,17 @@ func (app *application) startWebSocketServer(ctx context.Context, ip netip.Addr,
 	}
 	certPEMBlock, err := certs.GetCert("cert.pem")
 	if err != nil {
-		slog.Error("Error loading TLS certificate", err)
+		slog.Error(fmt.Sprintf("Error loading TLS certificate - %s", err.Error()))
 		return
 	}
 	keyPEMBlock, err := certs.GetCert("key.pem")
 	if err != nil {
-		slog.Error("Error loading TLS key", err)
+		slog.Error(fmt.Sprintf("Error loading TLS key - %s", err.Error()))
 		return
 	}
 	tlsConfig.Certificates[0], err = tls.X509KeyPair(certPEMBlock, keyPEMBlock)
 	if err != nil {
-		slog.Error("Error loading TLS certificate and key", err)
+		slog.Error(fmt.Sprintf("Error loading TLS certificate and key - %s", err.Error()))
 		return
 	}
 
@@ -60,7 +60,7 @@ func (app *application) startWebSocketServer(ctx context.Context, ip netip.Addr,
 		slog.Info(fmt.Sprintf("Shutting down WebSocket server on %s", address))
 
 		if err := srv.Shutdown(ctxShutdown); err != nil {
-			slog.Error("Error shutting down WebSocket server on %s - %s", address, err.Error())
+			slog.Error(fmt.Sprintf("Error shutting down WebSocket server on %s - %s", address, err.Error()))
 		}
 	}()
 